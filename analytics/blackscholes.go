@@ -0,0 +1,210 @@
+// Package analytics prices LedgerX option contracts with the Black-Scholes
+// model. Prices, strikes and spots are in dollars per unit of the
+// underlying asset; LedgerX quotes both option prices and strikes that way,
+// in cents, with each contract covering 1/Multiplier units.
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+)
+
+const (
+	// DerivativeTypeOption is the derivative_type of listed option contracts.
+	DerivativeTypeOption = "options_contract"
+
+	secondsPerYear = 365 * 24 * 60 * 60
+)
+
+var (
+	ErrNotAnOption = errors.New("analytics: contract is not an option")
+	ErrExpired     = errors.New("analytics: option has expired")
+	ErrNoQuote     = errors.New("analytics: book has no two-sided quote")
+	ErrNoSolution  = errors.New("analytics: price is outside the model's range")
+)
+
+// Option is the subset of a listed contract needed for pricing.
+type Option struct {
+	ContractID int64
+	Underlying string
+	IsCall     bool
+	Strike     float64 // dollars
+	Expiry     time.Time
+	Multiplier int32
+}
+
+// OptionFromContract converts listed contract metadata into an Option.
+func OptionFromContract(c ledgerx.ListContractsData) (Option, error) {
+	if c.DerivativeType != DerivativeTypeOption || c.StrikePrice <= 0 {
+		return Option{}, fmt.Errorf("%w: contract %d (%s)", ErrNotAnOption, c.ID, c.DerivativeType)
+	}
+	return Option{
+		ContractID: c.ID,
+		Underlying: c.UnderlyingAsset,
+		IsCall:     c.IsCall,
+		Strike:     CentsToDollars(int64(c.StrikePrice)),
+		Expiry:     c.DateExpires.Time,
+		Multiplier: c.Multiplier,
+	}, nil
+}
+
+// ContractSize is the number of units of the underlying one contract covers.
+func (o Option) ContractSize() float64 {
+	if o.Multiplier <= 0 {
+		return 1
+	}
+	return 1 / float64(o.Multiplier)
+}
+
+// YearsToExpiry is the time from now to expiry in years of 365 days. It is
+// zero once the option has expired.
+func (o Option) YearsToExpiry(now time.Time) float64 {
+	return years(now, o.Expiry)
+}
+
+// Market holds the inputs shared by every option on an underlying.
+type Market struct {
+	Spot float64 // dollars
+	Rate float64 // continuously compounded risk-free rate
+	Now  time.Time
+}
+
+// Greeks are per unit of the underlying. Vega is per volatility point
+// (0.01) and theta per calendar day.
+type Greeks struct {
+	Value float64
+	Delta float64
+	Gamma float64
+	Vega  float64
+	Theta float64
+}
+
+// Price returns the theoretical value and greeks of o at volatility vol.
+func Price(o Option, m Market, vol float64) Greeks {
+	return BlackScholes(o.IsCall, m.Spot, o.Strike, o.YearsToExpiry(m.Now), m.Rate, vol)
+}
+
+// BlackScholes prices a European option with time to expiry t in years.
+// At or after expiry, or with zero volatility, it returns intrinsic value
+// on the discounted forward.
+func BlackScholes(isCall bool, spot, strike, t, rate, vol float64) Greeks {
+	if t <= 0 || vol <= 0 {
+		return intrinsic(isCall, spot, strike, t, rate)
+	}
+
+	sqrtT := math.Sqrt(t)
+	d1 := (math.Log(spot/strike) + (rate+vol*vol/2)*t) / (vol * sqrtT)
+	d2 := d1 - vol*sqrtT
+	discount := math.Exp(-rate * t)
+	pdf := normPDF(d1)
+
+	g := Greeks{
+		Gamma: pdf / (spot * vol * sqrtT),
+		Vega:  spot * pdf * sqrtT / 100,
+	}
+	decay := -spot * pdf * vol / (2 * sqrtT)
+	if isCall {
+		g.Value = spot*normCDF(d1) - strike*discount*normCDF(d2)
+		g.Delta = normCDF(d1)
+		g.Theta = (decay - rate*strike*discount*normCDF(d2)) / 365
+	} else {
+		g.Value = strike*discount*normCDF(-d2) - spot*normCDF(-d1)
+		g.Delta = normCDF(d1) - 1
+		g.Theta = (decay + rate*strike*discount*normCDF(-d2)) / 365
+	}
+	return g
+}
+
+func intrinsic(isCall bool, spot, strike, t, rate float64) Greeks {
+	discounted := strike * math.Exp(-rate*math.Max(t, 0))
+	var g Greeks
+	if isCall && spot > discounted {
+		g.Value, g.Delta = spot-discounted, 1
+	} else if !isCall && spot < discounted {
+		g.Value, g.Delta = discounted-spot, -1
+	}
+	return g
+}
+
+const (
+	minVol        = 1e-4
+	maxVol        = 10.0
+	volTolerance  = 1e-8
+	maxIterations = 100
+)
+
+// ImpliedVol solves for the volatility at which o is worth price. It uses
+// Newton's method, falling back to bisection where vega is too small to
+// make progress.
+func ImpliedVol(o Option, m Market, price float64) (float64, error) {
+	t := o.YearsToExpiry(m.Now)
+	if t <= 0 {
+		return 0, fmt.Errorf("%w: contract %d", ErrExpired, o.ContractID)
+	}
+
+	lo, hi := minVol, maxVol
+	value := func(vol float64) float64 {
+		return BlackScholes(o.IsCall, m.Spot, o.Strike, t, m.Rate, vol).Value
+	}
+	if price < value(lo) || price > value(hi) {
+		return 0, fmt.Errorf("%w: contract %d price %.4f", ErrNoSolution, o.ContractID, price)
+	}
+
+	vol := 0.5
+	for i := 0; i < maxIterations; i++ {
+		g := BlackScholes(o.IsCall, m.Spot, o.Strike, t, m.Rate, vol)
+		diff := g.Value - price
+		if math.Abs(diff) < volTolerance {
+			return vol, nil
+		}
+		if diff > 0 {
+			hi = vol
+		} else {
+			lo = vol
+		}
+
+		next := vol - diff/(g.Vega*100)
+		if g.Vega <= 0 || math.IsNaN(next) || next <= lo || next >= hi {
+			next = (lo + hi) / 2
+		}
+		vol = next
+		if hi-lo < volTolerance {
+			return vol, nil
+		}
+	}
+	return vol, nil
+}
+
+// MidPrice returns the mid of a book_top message in dollars. Both sides of
+// the book must be quoted.
+func MidPrice(top ledgerx.TopBookResponse) (float64, error) {
+	if top.Bid <= 0 || top.Ask <= 0 || top.Ask < top.Bid {
+		return 0, fmt.Errorf("%w: contract %d bid %d ask %d", ErrNoQuote, top.ContractID, top.Bid, top.Ask)
+	}
+	return CentsToDollars(top.Bid+top.Ask) / 2, nil
+}
+
+// ImpliedVolFromBook solves for the volatility implied by the book mid.
+func ImpliedVolFromBook(o Option, m Market, top ledgerx.TopBookResponse) (float64, error) {
+	mid, err := MidPrice(top)
+	if err != nil {
+		return 0, err
+	}
+	return ImpliedVol(o, m, mid)
+}
+
+func CentsToDollars(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
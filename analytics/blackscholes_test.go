@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlackScholesReferenceValues(t *testing.T) {
+	// Hull, Options, Futures and Other Derivatives, example 15.6.
+	call := BlackScholes(true, 42, 40, 0.5, 0.1, 0.2)
+	put := BlackScholes(false, 42, 40, 0.5, 0.1, 0.2)
+	assert.InDelta(t, 4.76, call.Value, 0.005)
+	assert.InDelta(t, 0.81, put.Value, 0.005)
+
+	// Put-call parity.
+	assert.InDelta(t, call.Value-put.Value, 42-40*math.Exp(-0.1*0.5), 1e-9)
+	assert.InDelta(t, 1, call.Delta-put.Delta, 1e-9)
+	assert.InDelta(t, call.Gamma, put.Gamma, 1e-12)
+	assert.InDelta(t, call.Vega, put.Vega, 1e-12)
+}
+
+func TestGreeksMatchFiniteDifferences(t *testing.T) {
+	const spot, strike, years, rate, vol = 30000.0, 32000.0, 30.0 / 365, 0.02, 0.8
+	for _, isCall := range []bool{true, false} {
+		g := BlackScholes(isCall, spot, strike, years, rate, vol)
+		value := func(s, tt, v float64) float64 { return BlackScholes(isCall, s, strike, tt, rate, v).Value }
+
+		h := 1.0
+		assert.InDelta(t, (value(spot+h, years, vol)-value(spot-h, years, vol))/(2*h), g.Delta, 1e-6)
+		assert.InDelta(t, (value(spot+h, years, vol)-2*g.Value+value(spot-h, years, vol))/(h*h), g.Gamma, 1e-6)
+		assert.InDelta(t, (value(spot, years, vol+1e-4)-value(spot, years, vol-1e-4))/2e-4/100, g.Vega, 1e-4)
+		assert.InDelta(t, value(spot, years-1.0/365, vol)-g.Value, g.Theta, math.Abs(g.Theta)*0.02)
+	}
+}
+
+func TestBlackScholesAtExpiry(t *testing.T) {
+	assert.Equal(t, Greeks{Value: 2, Delta: 1}, BlackScholes(true, 42, 40, 0, 0.1, 0.2))
+	assert.Equal(t, Greeks{}, BlackScholes(false, 42, 40, 0, 0.1, 0.2))
+	assert.Equal(t, Greeks{Value: 3, Delta: -1}, BlackScholes(false, 37, 40, 0, 0.1, 0.2))
+}
+
+func testOption(now time.Time, isCall bool) Option {
+	contract := ledgerx.ListContractsData{
+		ID:              10,
+		IsCall:          isCall,
+		StrikePrice:     3500000,
+		DateExpires:     ledgerx.LedgerTime{Time: now.Add(60 * 24 * time.Hour)},
+		UnderlyingAsset: "CBTC",
+		DerivativeType:  DerivativeTypeOption,
+		Multiplier:      100,
+	}
+	o, err := OptionFromContract(contract)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+func TestOptionFromContract(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	o := testOption(now, true)
+	assert.Equal(t, 35000.0, o.Strike)
+	assert.Equal(t, 0.01, o.ContractSize())
+	assert.InDelta(t, 60.0/365, o.YearsToExpiry(now), 1e-12)
+	assert.Equal(t, 0.0, o.YearsToExpiry(now.Add(90*24*time.Hour)))
+
+	_, err := OptionFromContract(ledgerx.ListContractsData{ID: 1, DerivativeType: "day_ahead_swap"})
+	assert.True(t, errors.Is(err, ErrNotAnOption))
+}
+
+func TestImpliedVolRoundTrip(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := Market{Spot: 33000, Rate: 0.01, Now: now}
+	for _, isCall := range []bool{true, false} {
+		o := testOption(now, isCall)
+		for _, vol := range []float64{0.05, 0.4, 0.9, 2.5} {
+			price := Price(o, m, vol).Value
+			iv, err := ImpliedVol(o, m, price)
+			assert.Nil(t, err)
+			assert.InDelta(t, vol, iv, 1e-6, "call=%v vol=%v", isCall, vol)
+		}
+	}
+}
+
+func TestImpliedVolErrors(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := Market{Spot: 33000, Now: now}
+	o := testOption(now, true)
+
+	_, err := ImpliedVol(o, m, 40000) // above the spot
+	assert.True(t, errors.Is(err, ErrNoSolution))
+
+	_, err = ImpliedVol(o, Market{Spot: 33000, Now: now.Add(365 * 24 * time.Hour)}, 100)
+	assert.True(t, errors.Is(err, ErrExpired))
+}
+
+func TestImpliedVolFromBook(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := Market{Spot: 33000, Now: now}
+	o := testOption(now, true)
+	price := Price(o, m, 0.75).Value
+	cents := int64(math.Round(price * 100))
+
+	iv, err := ImpliedVolFromBook(o, m, ledgerx.TopBookResponse{ContractID: 10, Bid: cents - 1000, Ask: cents + 1000})
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.75, iv, 1e-4)
+
+	_, err = ImpliedVolFromBook(o, m, ledgerx.TopBookResponse{ContractID: 10, Bid: cents})
+	assert.True(t, errors.Is(err, ErrNoQuote))
+}
@@ -0,0 +1,275 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+)
+
+// VolSource supplies the volatility used to price an option. *Surface
+// satisfies it.
+type VolSource interface {
+	Vol(expiry time.Time, strike float64) (float64, bool)
+}
+
+// FlatVol is a VolSource returning the same volatility everywhere.
+type FlatVol float64
+
+func (v FlatVol) Vol(expiry time.Time, strike float64) (float64, bool) {
+	return float64(v), true
+}
+
+// UnderlyingGreeks aggregates the greeks of every position on one
+// underlying. Greeks are in units of the underlying for delta and gamma and
+// in dollars for value, vega and theta, scaled by position size and
+// contract size. Swaps and futures count as delta one.
+type UnderlyingGreeks struct {
+	Underlying string  `json:"underlying"`
+	Spot       float64 `json:"spot"`
+	Value      float64 `json:"value"`
+	Delta      float64 `json:"delta"`
+	Gamma      float64 `json:"gamma"`
+	Vega       float64 `json:"vega"`
+	Theta      float64 `json:"theta"`
+	Missing    []int64 `json:"missing,omitempty"` // contracts that could not be priced
+}
+
+// Scenario shocks the market for one underlying. SpotShock is relative
+// (0.1 is a 10% rally), VolShock is absolute (0.05 adds five vol points)
+// and Days moves the valuation date forward.
+type Scenario struct {
+	SpotShock float64 `json:"spot_shock"`
+	VolShock  float64 `json:"vol_shock"`
+	Days      float64 `json:"days"`
+}
+
+// ScenarioResult is the PnL of a portfolio under a scenario.
+type ScenarioResult struct {
+	Scenario
+	Underlying string  `json:"underlying"`
+	PnL        float64 `json:"pnl"`
+}
+
+// Portfolio prices positions with contract metadata from a
+// ContractLookup and volatilities from a VolSource per underlying.
+// Positions can be set from a PositionBook snapshot or the REST positions
+// endpoint and are kept current from open_positions_update messages when
+// the portfolio is registered as a message handler.
+type Portfolio struct {
+	lookup ledgerx.ContractLookup
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	rate      float64
+	positions map[int64]int64
+	spots     map[string]float64
+	vols      map[string]VolSource
+}
+
+func NewPortfolio(lookup ledgerx.ContractLookup) *Portfolio {
+	return &Portfolio{
+		lookup:    lookup,
+		now:       time.Now,
+		positions: make(map[int64]int64),
+		spots:     make(map[string]float64),
+		vols:      make(map[string]VolSource),
+	}
+}
+
+// SetPositions replaces the net position of every contract, for example
+// with PositionBook.Positions.
+func (p *Portfolio) SetPositions(positions map[int64]int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.positions = make(map[int64]int64, len(positions))
+	for contractID, size := range positions {
+		if size != 0 {
+			p.positions[contractID] = size
+		}
+	}
+}
+
+// SetPositionsFromREST replaces the positions with a REST snapshot.
+func (p *Portfolio) SetPositionsFromREST(positions []ledgerx.ListPositionsData) {
+	net := make(map[int64]int64, len(positions))
+	for _, position := range positions {
+		net[position.Contract.ID] += position.NetSize()
+	}
+	p.SetPositions(net)
+}
+
+func (p *Portfolio) HandleMessage(msg ledgerx.Message) {
+	update, ok := msg.Data.(ledgerx.OpenPositionsMessage)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, position := range update.Positions {
+		if position.Size == 0 {
+			delete(p.positions, position.ContractID)
+		} else {
+			p.positions[position.ContractID] = position.Size
+		}
+	}
+}
+
+// SetSpot sets the price of underlying in dollars.
+func (p *Portfolio) SetSpot(underlying string, spot float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spots[underlying] = spot
+}
+
+func (p *Portfolio) SetVolSource(underlying string, vols VolSource) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.vols[underlying] = vols
+}
+
+func (p *Portfolio) SetRate(rate float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rate = rate
+}
+
+// Greeks returns the aggregate greeks per underlying, sorted by
+// underlying. Contracts without metadata are reported under "unknown".
+func (p *Portfolio) Greeks() []UnderlyingGreeks {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := p.now()
+	byUnderlying := make(map[string]*UnderlyingGreeks)
+	for _, h := range p.holdingsLocked() {
+		agg, ok := byUnderlying[h.underlying]
+		if !ok {
+			agg = &UnderlyingGreeks{Underlying: h.underlying, Spot: p.spots[h.underlying]}
+			byUnderlying[h.underlying] = agg
+		}
+		g, ok := p.priceLocked(h, Scenario{}, now)
+		if !ok {
+			agg.Missing = append(agg.Missing, h.contractID)
+			continue
+		}
+		agg.Value += g.Value
+		agg.Delta += g.Delta
+		agg.Gamma += g.Gamma
+		agg.Vega += g.Vega
+		agg.Theta += g.Theta
+	}
+
+	result := make([]UnderlyingGreeks, 0, len(byUnderlying))
+	for _, agg := range byUnderlying {
+		sort.Slice(agg.Missing, func(i, j int) bool { return agg.Missing[i] < agg.Missing[j] })
+		result = append(result, *agg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Underlying < result[j].Underlying })
+	return result
+}
+
+// ScenarioPnL revalues the positions on underlying under s and returns the
+// change in value. It fails if any position cannot be priced.
+func (p *Portfolio) ScenarioPnL(underlying string, s Scenario) (ScenarioResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := p.now()
+	result := ScenarioResult{Scenario: s, Underlying: underlying}
+	for _, h := range p.holdingsLocked() {
+		if h.underlying != underlying {
+			continue
+		}
+		base, ok := p.priceLocked(h, Scenario{}, now)
+		shocked, shockedOK := p.priceLocked(h, s, now)
+		if !ok || !shockedOK {
+			return ScenarioResult{}, fmt.Errorf("analytics: cannot price contract %d on %s", h.contractID, underlying)
+		}
+		result.PnL += shocked.Value - base.Value
+	}
+	return result, nil
+}
+
+// ScenarioGrid returns the PnL of every combination of spot and vol
+// shocks, indexed [spot][vol].
+func (p *Portfolio) ScenarioGrid(underlying string, spotShocks []float64, volShocks []float64) ([][]ScenarioResult, error) {
+	grid := make([][]ScenarioResult, len(spotShocks))
+	for i, spotShock := range spotShocks {
+		grid[i] = make([]ScenarioResult, len(volShocks))
+		for j, volShock := range volShocks {
+			result, err := p.ScenarioPnL(underlying, Scenario{SpotShock: spotShock, VolShock: volShock})
+			if err != nil {
+				return nil, err
+			}
+			grid[i][j] = result
+		}
+	}
+	return grid, nil
+}
+
+type holding struct {
+	contractID int64
+	underlying string
+	size       int64
+	contract   ledgerx.ListContractsData
+	known      bool
+}
+
+func (p *Portfolio) holdingsLocked() []holding {
+	holdings := make([]holding, 0, len(p.positions))
+	for contractID, size := range p.positions {
+		h := holding{contractID: contractID, underlying: "unknown", size: size}
+		if p.lookup != nil {
+			if c, ok := p.lookup.Contract(contractID); ok {
+				h.contract, h.known = c, true
+				h.underlying = c.UnderlyingAsset
+			}
+		}
+		holdings = append(holdings, h)
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].contractID < holdings[j].contractID })
+	return holdings
+}
+
+// priceLocked returns the scaled greeks of a holding under s.
+func (p *Portfolio) priceLocked(h holding, s Scenario, now time.Time) (Greeks, bool) {
+	spot, ok := p.spots[h.underlying]
+	if !h.known || !ok || spot <= 0 {
+		return Greeks{}, false
+	}
+	spot *= 1 + s.SpotShock
+	now = now.Add(time.Duration(s.Days * float64(24*time.Hour)))
+
+	o, err := OptionFromContract(h.contract)
+	if err != nil {
+		// Delta one contracts.
+		scale := float64(h.size) * contractSize(h.contract.Multiplier)
+		return Greeks{Value: spot * scale, Delta: scale}, true
+	}
+
+	vols, ok := p.vols[h.underlying]
+	if !ok {
+		return Greeks{}, false
+	}
+	vol, ok := vols.Vol(o.Expiry, o.Strike)
+	if !ok {
+		return Greeks{}, false
+	}
+	g := Price(o, Market{Spot: spot, Rate: p.rate, Now: now}, math.Max(vol+s.VolShock, 0))
+	scale := float64(h.size) * o.ContractSize()
+	return Greeks{
+		Value: g.Value * scale,
+		Delta: g.Delta * scale,
+		Gamma: g.Gamma * scale,
+		Vega:  g.Vega * scale,
+		Theta: g.Theta * scale,
+	}, true
+}
+
+func contractSize(multiplier int32) float64 {
+	return Option{Multiplier: multiplier}.ContractSize()
+}
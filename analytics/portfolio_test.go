@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+	"github.com/stretchr/testify/assert"
+)
+
+type contractMap map[int64]ledgerx.ListContractsData
+
+func (m contractMap) Contract(id int64) (ledgerx.ListContractsData, bool) {
+	c, ok := m[id]
+	return c, ok
+}
+
+func testPortfolio() *Portfolio {
+	expiry := ledgerx.LedgerTime{Time: surfaceNow.Add(30 * 24 * time.Hour)}
+	contracts := contractMap{
+		1: {ID: 1, IsCall: true, StrikePrice: 3500000, DateExpires: expiry, UnderlyingAsset: "CBTC", DerivativeType: DerivativeTypeOption, Multiplier: 100},
+		2: {ID: 2, IsCall: false, StrikePrice: 3000000, DateExpires: expiry, UnderlyingAsset: "CBTC", DerivativeType: DerivativeTypeOption, Multiplier: 100},
+		3: {ID: 3, UnderlyingAsset: "CBTC", DerivativeType: "day_ahead_swap", Multiplier: 100},
+		4: {ID: 4, IsCall: true, StrikePrice: 200000, DateExpires: expiry, UnderlyingAsset: "ETH", DerivativeType: DerivativeTypeOption, Multiplier: 10},
+	}
+	p := NewPortfolio(contracts)
+	p.now = func() time.Time { return surfaceNow }
+	p.SetSpot("CBTC", 34000)
+	p.SetVolSource("CBTC", FlatVol(0.8))
+	return p
+}
+
+func TestPortfolioGreeks(t *testing.T) {
+	p := testPortfolio()
+	p.SetPositions(map[int64]int64{1: 10, 2: -5, 3: 20, 4: 1, 99: 3})
+
+	greeks := p.Greeks()
+	if !assert.Len(t, greeks, 3) {
+		return
+	}
+
+	o1, _ := OptionFromContract(ledgerx.ListContractsData{ID: 1, IsCall: true, StrikePrice: 3500000, DateExpires: ledgerx.LedgerTime{Time: surfaceNow.Add(30 * 24 * time.Hour)}, DerivativeType: DerivativeTypeOption, Multiplier: 100})
+	o2 := o1
+	o2.IsCall, o2.Strike = false, 30000
+	m := Market{Spot: 34000, Now: surfaceNow}
+	call, put := Price(o1, m, 0.8), Price(o2, m, 0.8)
+
+	cbtc := greeks[0]
+	assert.Equal(t, "CBTC", cbtc.Underlying)
+	assert.InDelta(t, 0.1*call.Delta-0.05*put.Delta+0.2, cbtc.Delta, 1e-9)
+	assert.InDelta(t, 0.1*call.Gamma-0.05*put.Gamma, cbtc.Gamma, 1e-12)
+	assert.InDelta(t, 0.1*call.Vega-0.05*put.Vega, cbtc.Vega, 1e-9)
+	assert.InDelta(t, 0.1*call.Theta-0.05*put.Theta, cbtc.Theta, 1e-9)
+	assert.Empty(t, cbtc.Missing)
+
+	// No spot or vols for ETH, and no metadata for contract 99.
+	assert.Equal(t, UnderlyingGreeks{Underlying: "ETH", Missing: []int64{4}}, greeks[1])
+	assert.Equal(t, UnderlyingGreeks{Underlying: "unknown", Missing: []int64{99}}, greeks[2])
+}
+
+func TestPortfolioPositionsFromFeed(t *testing.T) {
+	p := testPortfolio()
+	p.SetPositionsFromREST([]ledgerx.ListPositionsData{
+		{Type: "long", Size: 10, Contract: ledgerx.ListContractsData{ID: 3}},
+		{Type: "short", Size: 4, Contract: ledgerx.ListContractsData{ID: 1}},
+	})
+	greeks := p.Greeks()
+	assert.Len(t, greeks, 1)
+
+	p.HandleMessage(ledgerx.Message{Data: ledgerx.OpenPositionsMessage{Positions: []ledgerx.Position{
+		{ContractID: 1, Size: 0},
+		{ContractID: 3, Size: 30},
+	}}})
+	assert.InDelta(t, 0.3, p.Greeks()[0].Delta, 1e-12)
+}
+
+func TestPortfolioScenarioPnL(t *testing.T) {
+	p := testPortfolio()
+	p.SetPositions(map[int64]int64{1: 10, 3: -2})
+
+	flat, err := p.ScenarioPnL("CBTC", Scenario{})
+	assert.Nil(t, err)
+	assert.InDelta(t, 0, flat.PnL, 1e-9)
+
+	greeks := p.Greeks()[0]
+	small, _ := p.ScenarioPnL("CBTC", Scenario{SpotShock: 0.001})
+	assert.InDelta(t, greeks.Delta*34, small.PnL, 0.05, "small moves should follow delta")
+
+	vol, _ := p.ScenarioPnL("CBTC", Scenario{VolShock: 0.01})
+	assert.InDelta(t, greeks.Vega, vol.PnL, 0.05, "one vol point should follow vega")
+
+	day, _ := p.ScenarioPnL("CBTC", Scenario{Days: 1})
+	assert.InDelta(t, greeks.Theta, day.PnL, 0.05)
+
+	grid, err := p.ScenarioGrid("CBTC", []float64{-0.2, 0, 0.2}, []float64{-0.1, 0.1})
+	assert.Nil(t, err)
+	assert.Len(t, grid, 3)
+	assert.True(t, grid[2][1].PnL > grid[0][1].PnL)
+	assert.True(t, grid[1][1].PnL > grid[1][0].PnL, "long calls gain from higher vol")
+	assert.Equal(t, Scenario{SpotShock: 0.2, VolShock: -0.1}, grid[2][0].Scenario)
+
+	p.SetPositions(map[int64]int64{4: 1})
+	_, err = p.ScenarioPnL("ETH", Scenario{SpotShock: 0.1})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,312 @@
+package analytics
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+)
+
+// SurfacePoint is the implied volatility of one option contract.
+type SurfacePoint struct {
+	ContractID int64   `json:"contract_id"`
+	Strike     float64 `json:"strike"`
+	IsCall     bool    `json:"is_call"`
+	Mid        float64 `json:"mid"`
+	Vol        float64 `json:"vol"`
+}
+
+// SkewMetrics summarises the smile of one expiry using strikes at 90%
+// and 110% of spot.
+type SkewMetrics struct {
+	ATMVol       float64 `json:"atm_vol"`
+	Put90Vol     float64 `json:"put_90_vol"`
+	Call110Vol   float64 `json:"call_110_vol"`
+	RiskReversal float64 `json:"risk_reversal"` // call 110 minus put 90
+	Butterfly    float64 `json:"butterfly"`     // wing average minus ATM
+}
+
+// ExpirySlice is the smile for one expiry. Points are sorted by strike and
+// use the out-of-the-money option at each strike where both are quoted.
+type ExpirySlice struct {
+	Expiry time.Time      `json:"expiry"`
+	Years  float64        `json:"years"`
+	Points []SurfacePoint `json:"points"`
+	Skew   SkewMetrics    `json:"skew"`
+}
+
+// TermPoint is the ATM volatility of one expiry.
+type TermPoint struct {
+	Expiry time.Time `json:"expiry"`
+	Years  float64   `json:"years"`
+	Vol    float64   `json:"vol"`
+}
+
+// SurfaceSnapshot is a point-in-time copy of a Surface, suitable for JSON.
+type SurfaceSnapshot struct {
+	Underlying string        `json:"underlying"`
+	Spot       float64       `json:"spot"`
+	Time       time.Time     `json:"time"`
+	Expiries   []ExpirySlice `json:"expiries"`
+}
+
+// Surface maintains an implied volatility surface for the options on one
+// underlying, updated incrementally from book_top messages. The spot is
+// set with SetSpot or taken from the book mid of a reference contract set
+// with SetSpotContract. Register the surface with the client's message
+// handlers to keep it current.
+type Surface struct {
+	underlying string
+	now        func() time.Time
+
+	mu           sync.RWMutex
+	spot         float64
+	rate         float64
+	spotContract int64
+	options      map[int64]Option
+	books        map[int64]ledgerx.TopBookResponse
+	points       map[int64]SurfacePoint
+}
+
+func NewSurface(underlying string) *Surface {
+	return &Surface{
+		underlying: underlying,
+		now:        time.Now,
+		options:    make(map[int64]Option),
+		books:      make(map[int64]ledgerx.TopBookResponse),
+		points:     make(map[int64]SurfacePoint),
+	}
+}
+
+// SetContracts adds the option contracts on the surface's underlying.
+// Other contracts are ignored.
+func (s *Surface) SetContracts(contracts []ledgerx.ListContractsData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range contracts {
+		if c.UnderlyingAsset != s.underlying {
+			continue
+		}
+		if o, err := OptionFromContract(c); err == nil {
+			s.options[c.ID] = o
+			s.recomputeLocked(c.ID)
+		}
+	}
+}
+
+// SetSpot sets the underlying price in dollars and reprices every point.
+func (s *Surface) SetSpot(spot float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spot = spot
+	s.recomputeAllLocked()
+}
+
+// SetRate sets the risk-free rate and reprices every point.
+func (s *Surface) SetRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate = rate
+	s.recomputeAllLocked()
+}
+
+// SetSpotContract takes the spot from the book mid of contractID, for
+// example the next day swap on the underlying.
+func (s *Surface) SetSpotContract(contractID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spotContract = contractID
+}
+
+func (s *Surface) HandleMessage(msg ledgerx.Message) {
+	if top, ok := msg.Data.(ledgerx.TopBookResponse); ok {
+		s.Update(top)
+	}
+}
+
+// Update applies a book_top message. Quotes for unknown contracts are
+// ignored; a one-sided book removes the contract's point.
+func (s *Surface) Update(top ledgerx.TopBookResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.spotContract != 0 && top.ContractID == s.spotContract {
+		if mid, err := MidPrice(top); err == nil {
+			s.spot = mid
+			s.recomputeAllLocked()
+		}
+		return
+	}
+	if _, ok := s.options[top.ContractID]; !ok {
+		return
+	}
+	s.books[top.ContractID] = top
+	s.recomputeLocked(top.ContractID)
+}
+
+// Point returns the implied volatility of a single contract.
+func (s *Surface) Point(contractID int64) (SurfacePoint, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.points[contractID]
+	return p, ok
+}
+
+// Vol interpolates the smile for expiry linearly in strike, extrapolating
+// flat beyond the outermost quoted strikes.
+func (s *Surface) Vol(expiry time.Time, strike float64) (float64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return interpolate(s.smileLocked(expiry), strike)
+}
+
+// ATMTermStructure returns the at-the-money volatility of every quoted
+// expiry in expiry order.
+func (s *Surface) ATMTermStructure() []TermPoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	var term []TermPoint
+	for _, expiry := range s.expiriesLocked() {
+		if vol, ok := interpolate(s.smileLocked(expiry), s.spot); ok {
+			term = append(term, TermPoint{Expiry: expiry, Years: years(now, expiry), Vol: vol})
+		}
+	}
+	return term
+}
+
+// Skew returns the skew metrics for expiry.
+func (s *Surface) Skew(expiry time.Time) (SkewMetrics, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.skewLocked(s.smileLocked(expiry))
+}
+
+func (s *Surface) Snapshot() SurfaceSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	snapshot := SurfaceSnapshot{Underlying: s.underlying, Spot: s.spot, Time: now}
+	for _, expiry := range s.expiriesLocked() {
+		smile := s.smileLocked(expiry)
+		skew, _ := s.skewLocked(smile)
+		snapshot.Expiries = append(snapshot.Expiries, ExpirySlice{
+			Expiry: expiry,
+			Years:  years(now, expiry),
+			Points: smile,
+			Skew:   skew,
+		})
+	}
+	return snapshot
+}
+
+// WriteJSON writes a snapshot of the surface to w.
+func (s *Surface) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+func (s *Surface) recomputeAllLocked() {
+	for contractID := range s.books {
+		s.recomputeLocked(contractID)
+	}
+}
+
+func (s *Surface) recomputeLocked(contractID int64) {
+	delete(s.points, contractID)
+	top, ok := s.books[contractID]
+	if !ok || s.spot <= 0 {
+		return
+	}
+	o := s.options[contractID]
+	mid, err := MidPrice(top)
+	if err != nil {
+		return
+	}
+	vol, err := ImpliedVol(o, Market{Spot: s.spot, Rate: s.rate, Now: s.now()}, mid)
+	if err != nil {
+		return
+	}
+	s.points[contractID] = SurfacePoint{ContractID: contractID, Strike: o.Strike, IsCall: o.IsCall, Mid: mid, Vol: vol}
+}
+
+func (s *Surface) expiriesLocked() []time.Time {
+	seen := make(map[time.Time]bool)
+	var expiries []time.Time
+	for contractID := range s.points {
+		expiry := s.options[contractID].Expiry
+		if !seen[expiry] {
+			seen[expiry] = true
+			expiries = append(expiries, expiry)
+		}
+	}
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Before(expiries[j]) })
+	return expiries
+}
+
+// smileLocked returns one point per strike for expiry, preferring puts
+// below spot and calls at or above it.
+func (s *Surface) smileLocked(expiry time.Time) []SurfacePoint {
+	byStrike := make(map[float64]SurfacePoint)
+	for contractID, p := range s.points {
+		if !s.options[contractID].Expiry.Equal(expiry) {
+			continue
+		}
+		existing, ok := byStrike[p.Strike]
+		if !ok || isOTM(p, s.spot) && !isOTM(existing, s.spot) {
+			byStrike[p.Strike] = p
+		}
+	}
+	smile := make([]SurfacePoint, 0, len(byStrike))
+	for _, p := range byStrike {
+		smile = append(smile, p)
+	}
+	sort.Slice(smile, func(i, j int) bool { return smile[i].Strike < smile[j].Strike })
+	return smile
+}
+
+func (s *Surface) skewLocked(smile []SurfacePoint) (SkewMetrics, bool) {
+	atm, ok := interpolate(smile, s.spot)
+	if !ok {
+		return SkewMetrics{}, false
+	}
+	put, _ := interpolate(smile, 0.9*s.spot)
+	call, _ := interpolate(smile, 1.1*s.spot)
+	return SkewMetrics{
+		ATMVol:       atm,
+		Put90Vol:     put,
+		Call110Vol:   call,
+		RiskReversal: call - put,
+		Butterfly:    (put+call)/2 - atm,
+	}, true
+}
+
+func isOTM(p SurfacePoint, spot float64) bool {
+	if p.IsCall {
+		return p.Strike >= spot
+	}
+	return p.Strike < spot
+}
+
+func interpolate(smile []SurfacePoint, strike float64) (float64, bool) {
+	if len(smile) == 0 {
+		return 0, false
+	}
+	i := sort.Search(len(smile), func(i int) bool { return smile[i].Strike >= strike })
+	switch {
+	case i == 0:
+		return smile[0].Vol, true
+	case i == len(smile):
+		return smile[len(smile)-1].Vol, true
+	}
+	lo, hi := smile[i-1], smile[i]
+	w := (strike - lo.Strike) / (hi.Strike - lo.Strike)
+	return lo.Vol + w*(hi.Vol-lo.Vol), true
+}
+
+func years(now time.Time, expiry time.Time) float64 {
+	return math.Max(expiry.Sub(now).Seconds()/secondsPerYear, 0)
+}
@@ -0,0 +1,170 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+	"github.com/stretchr/testify/assert"
+)
+
+var surfaceNow = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// smileVol is a downward sloping smile that steepens at shorter expiries.
+func smileVol(strike, spot, years float64) float64 {
+	return 0.8 - 0.3*math.Log(strike/spot)/math.Sqrt(years*4) + 0.1*years
+}
+
+type surfaceFixture struct {
+	surface   *Surface
+	contracts []ledgerx.ListContractsData
+	expiries  []time.Time
+}
+
+func newSurfaceFixture(spot float64) *surfaceFixture {
+	f := &surfaceFixture{surface: NewSurface("CBTC")}
+	f.surface.now = func() time.Time { return surfaceNow }
+	f.surface.SetSpot(spot)
+
+	id := int64(1)
+	for _, days := range []int{7, 30, 90} {
+		expiry := surfaceNow.Add(time.Duration(days) * 24 * time.Hour)
+		f.expiries = append(f.expiries, expiry)
+		for _, strike := range []int32{2800000, 3200000, 3600000, 4000000} {
+			for _, isCall := range []bool{true, false} {
+				f.contracts = append(f.contracts, ledgerx.ListContractsData{
+					ID:              id,
+					IsCall:          isCall,
+					StrikePrice:     strike,
+					DateExpires:     ledgerx.LedgerTime{Time: expiry},
+					UnderlyingAsset: "CBTC",
+					DerivativeType:  DerivativeTypeOption,
+					Multiplier:      100,
+				})
+				id++
+			}
+		}
+	}
+	f.contracts = append(f.contracts, ledgerx.ListContractsData{ID: 500, UnderlyingAsset: "CBTC", DerivativeType: "day_ahead_swap"})
+	f.surface.SetContracts(f.contracts)
+	return f
+}
+
+// quoteAll publishes a tight book around the smile's theoretical prices.
+func (f *surfaceFixture) quoteAll(spot float64) {
+	for _, c := range f.contracts {
+		o, err := OptionFromContract(c)
+		if err != nil {
+			continue
+		}
+		m := Market{Spot: spot, Now: surfaceNow}
+		value := Price(o, m, smileVol(o.Strike, spot, o.YearsToExpiry(surfaceNow))).Value
+		cents := int64(math.Round(value * 100))
+		f.surface.HandleMessage(ledgerx.Message{
+			Type: ledgerx.ChanBookTop,
+			Data: ledgerx.TopBookResponse{ContractID: c.ID, Bid: cents - 1, Ask: cents + 1},
+		})
+	}
+}
+
+func TestSurfaceRecoversSmile(t *testing.T) {
+	f := newSurfaceFixture(34000)
+	f.quoteAll(34000)
+
+	for _, expiry := range f.expiries {
+		years := expiry.Sub(surfaceNow).Seconds() / secondsPerYear
+		for _, strike := range []float64{28000, 32000, 36000, 40000} {
+			vol, ok := f.surface.Vol(expiry, strike)
+			assert.True(t, ok)
+			assert.InDelta(t, smileVol(strike, 34000, years), vol, 2e-3, "expiry %s strike %v", expiry, strike)
+		}
+	}
+
+	// Interpolates between strikes and extrapolates flat.
+	v32, _ := f.surface.Vol(f.expiries[1], 32000)
+	v36, _ := f.surface.Vol(f.expiries[1], 36000)
+	mid, _ := f.surface.Vol(f.expiries[1], 34000)
+	assert.InDelta(t, (v32+v36)/2, mid, 1e-12)
+	v40, _ := f.surface.Vol(f.expiries[1], 40000)
+	far, _ := f.surface.Vol(f.expiries[1], 60000)
+	assert.Equal(t, v40, far)
+
+	_, ok := f.surface.Vol(surfaceNow, 34000)
+	assert.False(t, ok)
+}
+
+func TestSurfaceTermStructureAndSkew(t *testing.T) {
+	f := newSurfaceFixture(34000)
+	f.quoteAll(34000)
+
+	term := f.surface.ATMTermStructure()
+	if assert.Len(t, term, 3) {
+		for i, p := range term {
+			assert.Equal(t, f.expiries[i], p.Expiry)
+		}
+		assert.InDelta(t, 30.0/365, term[1].Years, 1e-12)
+	}
+
+	short, ok := f.surface.Skew(f.expiries[0])
+	assert.True(t, ok)
+	long, _ := f.surface.Skew(f.expiries[2])
+	assert.True(t, short.RiskReversal < 0, "puts should be bid over calls")
+	assert.True(t, short.RiskReversal < long.RiskReversal, "short expiries should be steeper")
+	assert.InDelta(t, short.Call110Vol-short.Put90Vol, short.RiskReversal, 1e-12)
+}
+
+func TestSurfaceIncrementalUpdates(t *testing.T) {
+	f := newSurfaceFixture(34000)
+	f.surface.SetSpotContract(500)
+	f.quoteAll(34000)
+
+	p, ok := f.surface.Point(1)
+	assert.True(t, ok)
+	assert.Equal(t, 28000.0, p.Strike)
+
+	// A one-sided book removes the point.
+	f.surface.Update(ledgerx.TopBookResponse{ContractID: 1, Bid: 100})
+	_, ok = f.surface.Point(1)
+	assert.False(t, ok)
+
+	// Unknown contracts are ignored.
+	f.surface.Update(ledgerx.TopBookResponse{ContractID: 9999, Bid: 100, Ask: 200})
+	_, ok = f.surface.Point(9999)
+	assert.False(t, ok)
+
+	// A spot move through the reference contract reprices every point.
+	f.quoteAll(34000)
+	before, _ := f.surface.Point(3)
+	f.surface.Update(ledgerx.TopBookResponse{ContractID: 500, Bid: 3490000, Ask: 3510000})
+	after, _ := f.surface.Point(3)
+	assert.Equal(t, 35000.0, f.surface.Snapshot().Spot)
+	assert.NotEqual(t, before.Vol, after.Vol)
+}
+
+func TestSurfaceSnapshotJSON(t *testing.T) {
+	f := newSurfaceFixture(34000)
+	f.quoteAll(34000)
+
+	var buf bytes.Buffer
+	assert.Nil(t, f.surface.WriteJSON(&buf))
+
+	var snapshot SurfaceSnapshot
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &snapshot))
+	assert.Equal(t, "CBTC", snapshot.Underlying)
+	assert.Equal(t, 34000.0, snapshot.Spot)
+	if assert.Len(t, snapshot.Expiries, 3) {
+		points := snapshot.Expiries[0].Points
+		if assert.Len(t, points, 4) {
+			// Out-of-the-money options are preferred at each strike.
+			assert.False(t, points[0].IsCall)
+			assert.False(t, points[1].IsCall)
+			assert.True(t, points[2].IsCall)
+			assert.True(t, points[3].IsCall)
+		}
+		assert.NotZero(t, snapshot.Expiries[0].Skew.ATMVol)
+	}
+	assert.Contains(t, buf.String(), `"risk_reversal"`)
+}
@@ -0,0 +1,197 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	AssetUSD  = "USD"
+	AssetBTC  = "BTC"
+	AssetCBTC = "CBTC"
+	AssetETH  = "ETH"
+)
+
+// AssetRegistry records the number of decimal places in the smallest unit
+// LedgerX uses for each asset, e.g. cents for USD.
+type AssetRegistry struct {
+	mu         sync.RWMutex
+	precisions map[string]int
+}
+
+// DefaultAssets is the registry used by balance formatting helpers. Register
+// new collateral assets here as LedgerX lists them.
+var DefaultAssets = NewAssetRegistry()
+
+func NewAssetRegistry() *AssetRegistry {
+	r := &AssetRegistry{precisions: make(map[string]int)}
+	r.Register(AssetUSD, 2)
+	r.Register(AssetBTC, 8)
+	r.Register(AssetCBTC, 8)
+	r.Register(AssetETH, 9)
+	return r
+}
+
+func (r *AssetRegistry) Register(asset string, precision int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.precisions[asset] = precision
+}
+
+// Precision returns the decimal places of asset. Unknown assets report
+// zero, meaning amounts are shown in their raw units.
+func (r *AssetRegistry) Precision(asset string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	precision, ok := r.precisions[asset]
+	return precision, ok
+}
+
+func (r *AssetRegistry) Assets() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	assets := make([]string, 0, len(r.precisions))
+	for asset := range r.precisions {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// Float converts an amount in the asset's smallest unit to whole units.
+func (r *AssetRegistry) Float(asset string, amount int64) float64 {
+	precision, _ := r.Precision(asset)
+	value := float64(amount)
+	for i := 0; i < precision; i++ {
+		value /= 10
+	}
+	return value
+}
+
+// Format renders an amount in whole units without floating point error.
+func (r *AssetRegistry) Format(asset string, amount int64) string {
+	precision, _ := r.Precision(asset)
+	if precision == 0 {
+		return strconv.FormatInt(amount, 10)
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if len(digits) <= precision {
+		digits = strings.Repeat("0", precision-len(digits)+1) + digits
+	}
+	split := len(digits) - precision
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// LedgerBalance holds amounts in each asset's smallest unit. The assets
+// LedgerX has always listed have fields; any other asset in the response
+// is kept in Other. Get and Set look up any asset by name.
+type LedgerBalance struct {
+	BTC   int64            `json:"BTC"`
+	CBTC  int64            `json:"CBTC"`
+	USD   int64            `json:"USD"`
+	ETH   int64            `json:"ETH"`
+	Other map[string]int64 `json:"-"`
+}
+
+// Get returns the balance of asset, zero if it is not present.
+func (b LedgerBalance) Get(asset string) int64 {
+	switch asset {
+	case AssetBTC:
+		return b.BTC
+	case AssetCBTC:
+		return b.CBTC
+	case AssetUSD:
+		return b.USD
+	case AssetETH:
+		return b.ETH
+	default:
+		return b.Other[asset]
+	}
+}
+
+// Set sets the balance of asset.
+func (b *LedgerBalance) Set(asset string, amount int64) {
+	switch asset {
+	case AssetBTC:
+		b.BTC = amount
+	case AssetCBTC:
+		b.CBTC = amount
+	case AssetUSD:
+		b.USD = amount
+	case AssetETH:
+		b.ETH = amount
+	default:
+		if b.Other == nil {
+			b.Other = make(map[string]int64)
+		}
+		b.Other[asset] = amount
+	}
+}
+
+// Assets returns the assets with a non-zero field balance, and every asset
+// in Other, sorted.
+func (b LedgerBalance) Assets() []string {
+	var assets []string
+	for _, asset := range []string{AssetBTC, AssetCBTC, AssetUSD, AssetETH} {
+		if b.Get(asset) != 0 {
+			assets = append(assets, asset)
+		}
+	}
+	for asset := range b.Other {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// Add returns the sum of two balances.
+func (b LedgerBalance) Add(other LedgerBalance) LedgerBalance {
+	sum := LedgerBalance{
+		BTC:  b.BTC + other.BTC,
+		CBTC: b.CBTC + other.CBTC,
+		USD:  b.USD + other.USD,
+		ETH:  b.ETH + other.ETH,
+	}
+	for _, balance := range []LedgerBalance{b, other} {
+		for asset, amount := range balance.Other {
+			sum.Set(asset, sum.Get(asset)+amount)
+		}
+	}
+	return sum
+}
+
+func (b *LedgerBalance) UnmarshalJSON(data []byte) error {
+	var amounts map[string]int64
+	if err := json.Unmarshal(data, &amounts); err != nil {
+		return err
+	}
+	*b = LedgerBalance{}
+	for asset, amount := range amounts {
+		b.Set(asset, amount)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the fields followed by the other assets.
+func (b LedgerBalance) MarshalJSON() ([]byte, error) {
+	type fields LedgerBalance
+	data, err := json.Marshal(fields(b))
+	if err != nil || len(b.Other) == 0 {
+		return data, err
+	}
+	other, err := json.Marshal(b.Other)
+	if err != nil {
+		return nil, err
+	}
+	data[len(data)-1] = ','
+	return append(data, other[1:]...), nil
+}
@@ -0,0 +1,51 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgerBalanceKeepsUnknownAssets(t *testing.T) {
+	var update BalanceUpdateMessage
+	err := json.Unmarshal([]byte(`{"collateral": {"available_balances": {"USD": 1050, "CBTC": 2, "SOL": 7}}}`), &update)
+	assert.Nil(t, err)
+
+	balance := update.Collateral.AvailableBalances
+	assert.Equal(t, int64(1050), balance.USD)
+	assert.Equal(t, int64(2), balance.CBTC)
+	assert.Equal(t, int64(0), balance.ETH)
+	assert.Equal(t, int64(7), balance.Get("SOL"), "should keep new assets")
+	assert.Equal(t, []string{"CBTC", "SOL", "USD"}, balance.Assets())
+	assert.Equal(t, LedgerBalance{USD: 1100, CBTC: 2, Other: map[string]int64{"SOL": 7}}, balance.Add(LedgerBalance{USD: 50}))
+
+	b, err := json.Marshal(balance)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"BTC":0,"CBTC":2,"USD":1050,"ETH":0,"SOL":7}`, string(b))
+}
+
+func TestAssetRegistryFormat(t *testing.T) {
+	registry := NewAssetRegistry()
+	registry.Register("SOL", 4)
+
+	assert.Equal(t, "10.50", registry.Format(AssetUSD, 1050))
+	assert.Equal(t, "-0.05", registry.Format(AssetUSD, -5))
+	assert.Equal(t, "0.00000001", registry.Format(AssetBTC, 1))
+	assert.Equal(t, "1.2345", registry.Format("SOL", 12345))
+	assert.Equal(t, "42", registry.Format("DOGE", 42), "unknown assets use raw units")
+	assert.Equal(t, 10.5, registry.Float(AssetUSD, 1050))
+}
+
+func TestCollateralSummaryIncludesNewAssets(t *testing.T) {
+	tracker := NewCollateralTracker()
+	tracker.Update(Collateral{
+		AvailableBalances:   LedgerBalance{USD: 750},
+		OrderLockedBalances: LedgerBalance{USD: 250, Other: map[string]int64{"SOL": 3}},
+	})
+
+	assert.Equal(t, []AssetSummary{
+		{Asset: "SOL", Locked: 3, Equity: 3, Utilization: 1, FormattedAvailable: "0", FormattedEquity: "3"},
+		{Asset: "USD", Available: 750, Locked: 250, Equity: 1000, Utilization: 0.25, FormattedAvailable: "7.50", FormattedEquity: "10.00"},
+	}, tracker.Summary())
+}
@@ -0,0 +1,78 @@
+package ledgerx
+
+import (
+	"sync"
+)
+
+// DefaultBatchWorkers bounds concurrent requests when a batch call is given
+// no worker count.
+const DefaultBatchWorkers = 8
+
+type BatchOrderResult struct {
+	Response *CreateOrderResponse
+	Err      error
+}
+
+type ReplaceOrder struct {
+	Mid     string
+	Request *CancelAndReplaceRequest
+}
+
+// CreateOrders submits orders concurrently using at most workers requests
+// at a time. Results are returned in input order. Each order goes through
+// CreateOrder, so rate limits, risk checks and halts apply per order. The
+// risk manager reserves each order's exposure while it is in flight, so
+// the batch as a whole stays within its limits.
+func (l *LedgerX) CreateOrders(requests []*CreateOrderRequest, workers int) []BatchOrderResult {
+	results := make([]BatchOrderResult, len(requests))
+	runBatch(len(requests), workers, func(i int) {
+		resp, err := l.CreateOrder(requests[i])
+		results[i] = BatchOrderResult{Response: resp, Err: err}
+	})
+	return results
+}
+
+// CancelAndReplaceOrders amends orders concurrently using at most workers
+// requests at a time, returning errors in input order.
+func (l *LedgerX) CancelAndReplaceOrders(replacements []ReplaceOrder, workers int) []error {
+	errs := make([]error, len(replacements))
+	runBatch(len(replacements), workers, func(i int) {
+		errs[i] = l.CancelAndReplaceOrder(replacements[i].Mid, replacements[i].Request)
+	})
+	return errs
+}
+
+// CancelOrders cancels orders concurrently, returning errors in input order.
+func (l *LedgerX) CancelOrders(orders []ListOpenOrdersData, workers int) []error {
+	errs := make([]error, len(orders))
+	runBatch(len(orders), workers, func(i int) {
+		errs[i] = l.CancelOrder(orders[i].Mid, int32(orders[i].ContractID))
+	})
+	return errs
+}
+
+func runBatch(n int, workers int, fn func(i int)) {
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
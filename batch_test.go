@@ -0,0 +1,113 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateOrdersReturnsResultsInInputOrder(t *testing.T) {
+	var active, peak int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		var request CreateOrderRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		// Later orders respond first to shuffle completion order.
+		time.Sleep(time.Duration(20-request.Price) * time.Millisecond)
+		if request.Price == 5 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"message": "INVALID_ORDER", "code": 602}}`))
+			return
+		}
+		fmt.Fprintf(w, `{"data": {"mid": "mid-%d"}}`, request.Price)
+	}))
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "")
+	var requests []*CreateOrderRequest
+	for price := int32(1); price <= 10; price++ {
+		requests = append(requests, Buy(1, 1).Limit(price))
+	}
+
+	results := ledgerClient.CreateOrders(requests, 3)
+	assert.Len(t, results, 10)
+	for i, result := range results {
+		price := i + 1
+		if price == 5 {
+			assert.NotNil(t, result.Err, "should report per-order errors")
+			continue
+		}
+		assert.Nil(t, result.Err)
+		assert.Equal(t, fmt.Sprintf("mid-%d", price), result.Response.Data.Mid)
+	}
+	assert.True(t, atomic.LoadInt32(&peak) <= 3, "should bound concurrency")
+}
+
+func TestCreateOrdersReservesRiskLimits(t *testing.T) {
+	var submitted int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&submitted, 1)
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintf(w, `{"data": {"mid": "mid-%d"}}`, n)
+	}))
+	defer s.Close()
+
+	risk := NewRiskManager(RiskLimits{MaxOpenOrders: 3, MaxNotional: 1000})
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRiskManager(risk))
+
+	var requests []*CreateOrderRequest
+	for i := 0; i < 6; i++ {
+		requests = append(requests, Buy(1, 1).Limit(200))
+	}
+	results := ledgerClient.CreateOrders(requests, 6)
+
+	accepted := 0
+	for _, result := range results {
+		var riskErr *RiskError
+		if result.Err == nil {
+			accepted++
+		} else {
+			assert.True(t, errors.As(result.Err, &riskErr), "extra orders should be rejected by the risk manager")
+		}
+	}
+	assert.Equal(t, 3, accepted, "concurrent submits should not exceed MaxOpenOrders")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&submitted))
+	assert.NotNil(t, risk.CheckOrder(Buy(1, 1).Limit(200)), "accepted orders should count until reported")
+}
+
+func TestCancelAndReplaceOrders(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "")
+	errs := ledgerClient.CancelAndReplaceOrders([]ReplaceOrder{
+		{Mid: "a", Request: &CancelAndReplaceRequest{ContractID: 1, Size: 1, Price: 100}},
+		{Mid: "missing", Request: &CancelAndReplaceRequest{ContractID: 1, Size: 1, Price: 100}},
+		{Mid: "b", Request: &CancelAndReplaceRequest{ContractID: 1, Size: 1, Price: 100}},
+	}, 0)
+	assert.Nil(t, errs[0])
+	assert.NotNil(t, errs[1])
+	assert.Nil(t, errs[2])
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+)
+
+func (c *cli) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+func (c *cli) contracts(args []string) error {
+	flags := c.flags("contracts")
+	asset := flags.String("asset", "", "only contracts on this underlying asset")
+	label := flags.String("label", "", "only contracts whose label contains this text")
+	active := flags.Bool("active", false, "only active contracts")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	listed, err := c.listContracts(*active)
+	if err != nil {
+		return err
+	}
+	contracts := []ledgerx.ListContractsData{}
+	var rows [][]string
+	for _, contract := range listed {
+		if *asset != "" && !strings.EqualFold(contract.UnderlyingAsset, *asset) {
+			continue
+		}
+		if *label != "" && !strings.Contains(strings.ToLower(contract.Label), strings.ToLower(*label)) {
+			continue
+		}
+		if *active && !contract.Active {
+			continue
+		}
+		contracts = append(contracts, contract)
+		rows = append(rows, []string{
+			strconv.FormatInt(contract.ID, 10),
+			contract.Label,
+			contract.DerivativeType,
+			contract.UnderlyingAsset,
+			formatTime(contract.DateExpires.Time),
+			strconv.FormatBool(contract.Active),
+		})
+	}
+	return c.out.print(contracts, []string{"ID", "LABEL", "TYPE", "UNDERLYING", "EXPIRES", "ACTIVE"}, rows)
+}
+
+// listContracts returns the active contracts of every derivative type,
+// followed, unless activeOnly, by recently listed day ahead swaps that are
+// no longer active.
+func (c *cli) listContracts(activeOnly bool) ([]ledgerx.ListContractsData, error) {
+	registry := ledgerx.NewContractRegistry(c.client)
+	if err := registry.Refresh(); err != nil {
+		return nil, err
+	}
+	contracts := registry.Contracts()
+	if activeOnly {
+		return contracts, nil
+	}
+
+	recent, err := c.client.ListContracts()
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[int64]bool, len(contracts))
+	for _, contract := range contracts {
+		listed[contract.ID] = true
+	}
+	for _, contract := range recent.Data {
+		if !listed[contract.ID] {
+			listed[contract.ID] = true
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts, nil
+}
+
+func (c *cli) orders(args []string) error {
+	flags := c.flags("orders")
+	contractID := flags.Int64("contract", 0, "only orders on this contract")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	resp, err := c.client.ListOpenOrders()
+	if err != nil {
+		return err
+	}
+	orders := []ledgerx.ListOpenOrdersData{}
+	var rows [][]string
+	for _, order := range resp.Data {
+		if *contractID != 0 && order.ContractID != *contractID {
+			continue
+		}
+		orders = append(orders, order)
+		rows = append(rows, []string{
+			order.Mid,
+			strconv.FormatInt(order.ContractID, 10),
+			string(ledgerx.SideFromIsAsk(order.IsAsk)),
+			cents(order.InsertedPrice),
+			strconv.FormatInt(order.InsertedSize-order.FilledSize, 10),
+			strconv.FormatInt(order.FilledSize, 10),
+			order.OrderType,
+		})
+	}
+	return c.out.print(orders, []string{"MID", "CONTRACT", "SIDE", "PRICE", "OPEN", "FILLED", "TYPE"}, rows)
+}
+
+func (c *cli) buy(args []string) error {
+	return c.placeOrder("buy", ledgerx.SideBid, args)
+}
+
+func (c *cli) sell(args []string) error {
+	return c.placeOrder("sell", ledgerx.SideAsk, args)
+}
+
+func (c *cli) placeOrder(name string, side ledgerx.Side, args []string) error {
+	flags := c.flags(name)
+	contractID := flags.Int64("contract", 0, "contract ID (required)")
+	size := flags.Int("size", 0, "number of contracts (required)")
+	price := flags.Int("price", 0, "limit price in cents")
+	market := flags.Bool("market", false, "place a market order instead of a limit order")
+	purpose := flags.String("swap-purpose", string(ledgerx.SwapPurposeUndisclosed), "swap purpose: undisclosed, bf_hedge or non_bf_hedge")
+	volatile := flags.Bool("volatile", false, "mark the order volatile")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *contractID == 0 || *size == 0 {
+		return errors.New("-contract and -size are required")
+	}
+
+	request := ledgerx.Buy(*contractID, int32(*size))
+	if side == ledgerx.SideAsk {
+		request = ledgerx.Sell(*contractID, int32(*size))
+	}
+	if *market {
+		request.Market()
+	} else {
+		request.Limit(int32(*price))
+	}
+	request.WithSwapPurpose(ledgerx.SwapPurpose(*purpose)).WithVolatile(*volatile)
+
+	resp, err := c.client.CreateOrder(request)
+	if err != nil {
+		return err
+	}
+	return c.out.print(resp.Data, []string{"MID"}, [][]string{{resp.Data.Mid}})
+}
+
+func (c *cli) cancel(args []string) error {
+	flags := c.flags("cancel")
+	mid := flags.String("mid", "", "order ID (required)")
+	contractID := flags.Int64("contract", 0, "contract ID (required)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *mid == "" || *contractID == 0 {
+		return errors.New("-mid and -contract are required")
+	}
+
+	if err := c.client.CancelOrder(*mid, int32(*contractID)); err != nil {
+		return err
+	}
+	return c.status(*mid, "cancelled")
+}
+
+func (c *cli) replace(args []string) error {
+	flags := c.flags("replace")
+	mid := flags.String("mid", "", "order ID (required)")
+	contractID := flags.Int64("contract", 0, "contract ID (required)")
+	size := flags.Int("size", 0, "new number of contracts (required)")
+	price := flags.Int("price", 0, "new limit price in cents (required)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *mid == "" || *contractID == 0 || *size == 0 || *price == 0 {
+		return errors.New("-mid, -contract, -size and -price are required")
+	}
+
+	err := c.client.CancelAndReplaceOrder(*mid, &ledgerx.CancelAndReplaceRequest{
+		ContractID: int32(*contractID),
+		Size:       int32(*size),
+		Price:      int32(*price),
+	})
+	if err != nil {
+		return err
+	}
+	return c.status(*mid, "replaced")
+}
+
+func (c *cli) cancelAll(args []string) error {
+	flags := c.flags("cancel-all")
+	contractID := flags.Int64("contract", 0, "only orders on this contract")
+	side := flags.String("side", "", "only orders on this side: bid or ask")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *side != "" && *side != string(ledgerx.SideBid) && *side != string(ledgerx.SideAsk) {
+		return fmt.Errorf("unknown side %q", *side)
+	}
+
+	if err := c.client.CancelAll(&ledgerx.CancelAllFilter{ContractID: *contractID, Side: ledgerx.Side(*side)}); err != nil {
+		return err
+	}
+	return c.status("all", "cancelled")
+}
+
+func (c *cli) status(id string, status string) error {
+	v := map[string]string{"id": id, "status": status}
+	return c.out.print(v, []string{"ID", "STATUS"}, [][]string{{id, status}})
+}
+
+func (c *cli) positions(args []string) error {
+	flags := c.flags("positions")
+	all := flags.Bool("all", false, "include flat positions")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	positions := []ledgerx.ListPositionsData{}
+	var offset int32
+	for {
+		resp, err := c.client.GetPositions(offset)
+		if err != nil {
+			return err
+		}
+		for _, p := range resp.Data {
+			if *all || p.NetSize() != 0 {
+				positions = append(positions, p)
+			}
+		}
+		offset += int32(len(resp.Data))
+		if len(resp.Data) < ledgerx.DefaultPageSize || int64(offset) >= resp.Metadata.TotalCount {
+			break
+		}
+	}
+
+	var rows [][]string
+	for _, p := range positions {
+		rows = append(rows, []string{
+			strconv.FormatInt(p.Contract.ID, 10),
+			p.Contract.Label,
+			strconv.FormatInt(p.NetSize(), 10),
+			strconv.FormatInt(p.ExercisedSize, 10),
+			strconv.FormatInt(p.AssignedSize, 10),
+		})
+	}
+	return c.out.print(positions, []string{"CONTRACT", "LABEL", "SIZE", "EXERCISED", "ASSIGNED"}, rows)
+}
+
+// balances waits for the collateral_balance_update the exchange sends
+// after connecting, as there is no REST endpoint for balances.
+func (c *cli) balances(args []string) error {
+	flags := c.flags("balances")
+	timeout := flags.Duration("timeout", 10*time.Second, "how long to wait for a balance update")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	tracker := ledgerx.NewCollateralTracker()
+	c.client.AddMessageHandler(tracker)
+	if err := c.client.Connect(); err != nil {
+		return err
+	}
+
+	deadline := time.After(*timeout)
+	for {
+		select {
+		case msg := <-c.client.Listen():
+			if msg.Type != ledgerx.ChanBalanceUpdate {
+				continue
+			}
+			summary := tracker.Summary()
+			var rows [][]string
+			for _, s := range summary {
+				rows = append(rows, []string{
+					s.Asset,
+					s.FormattedAvailable,
+					ledgerx.DefaultAssets.Format(s.Asset, s.Locked),
+					s.FormattedEquity,
+					fmt.Sprintf("%.1f%%", s.Utilization*100),
+				})
+			}
+			return c.out.print(summary, []string{"ASSET", "AVAILABLE", "LOCKED", "EQUITY", "UTILIZATION"}, rows)
+		case <-deadline:
+			return fmt.Errorf("no balance update within %s", *timeout)
+		}
+	}
+}
+
+func (c *cli) tail(args []string) error {
+	flags := c.flags("tail")
+	types := flags.String("types", "", "comma separated message types to show, e.g. book_top,action_report")
+	contractID := flags.Int64("contract", 0, "only book_top and action_report messages for this contract")
+	count := flags.Int("n", 0, "exit after this many messages")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			wanted[t] = true
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := c.client.Connect(); err != nil {
+		return err
+	}
+
+	shown := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-c.client.Listen():
+			if !ok {
+				return nil
+			}
+			if len(wanted) > 0 && !wanted[msg.Type] {
+				continue
+			}
+			if *contractID != 0 && messageContract(msg) != *contractID {
+				continue
+			}
+			if err := c.writeMessage(msg); err != nil {
+				return err
+			}
+			shown++
+			if *count > 0 && shown >= *count {
+				return nil
+			}
+		}
+	}
+}
+
+func messageContract(msg ledgerx.Message) int64 {
+	switch data := msg.Data.(type) {
+	case ledgerx.TopBookResponse:
+		return data.ContractID
+	case ledgerx.ActionReportResponse:
+		return data.ContractID
+	}
+	return 0
+}
+
+// writeMessage writes one message per line so the output can be piped.
+func (c *cli) writeMessage(msg ledgerx.Message) error {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+	if c.out.json {
+		_, err = fmt.Fprintf(c.stdout, "{\"type\":%q,\"data\":%s}\n", msg.Type, data)
+	} else {
+		_, err = fmt.Fprintf(c.stdout, "%s  %-28s %s\n", time.Now().UTC().Format("15:04:05.000"), msg.Type, data)
+	}
+	return err
+}
+
+func (c *cli) export(args []string) error {
+	flags := c.flags("export")
+	from := flags.String("from", "", "first day to export, YYYY-MM-DD (required)")
+	to := flags.String("to", "", "day after the last day to export, YYYY-MM-DD (required)")
+	fileFormat := flags.String("file-format", string(ledgerx.ExportCSV), "csv or ndjson")
+	out := flags.String("out", "", "file to append to (default stdout)")
+	after := flags.Int64("after", 0, "only trades after this trade ID")
+	state := flags.String("state", "", "file recording the last exported trade ID, to resume from")
+	types := flags.String("types", "", "comma separated derivative types (default all)")
+	asset := flags.String("asset", "", "only trades on this asset")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fromTime, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	toTime, err := time.Parse("2006-01-02", *to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	afterID := *after
+	if *state != "" {
+		data, err := os.ReadFile(*state)
+		switch {
+		case err == nil:
+			if afterID, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err != nil {
+				return fmt.Errorf("invalid state file %s: %w", *state, err)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+
+	var w io.Writer = c.stdout
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	exporter := ledgerx.NewTradeExporter(c.client)
+	exporter.Asset = *asset
+	if *types != "" {
+		exporter.DerivativeTypes = strings.Split(*types, ",")
+	}
+	result, err := exporter.Export(w, ledgerx.ExportOptions{
+		Format:  ledgerx.ExportFormat(*fileFormat),
+		From:    fromTime,
+		To:      toTime,
+		AfterID: afterID,
+	})
+	// Record progress even when the export fails, so it can be resumed.
+	if *state != "" && result.LastID != afterID {
+		if err := os.WriteFile(*state, []byte(strconv.FormatInt(result.LastID, 10)+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "exported %d trades, last trade ID %d\n", result.Trades, result.LastID)
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04")
+}
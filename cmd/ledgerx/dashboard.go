@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+)
+
+const (
+	ansiClear = "\x1b[H\x1b[2J"
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+
+	maxBookRows = 20
+)
+
+type dashboardOrder struct {
+	mid        string
+	contractID int64
+	side       ledgerx.Side
+	price      int64
+	size       int64
+	filled     int64
+}
+
+// dashboard holds the state rendered by the dashboard command. It is fed
+// from the websocket feed or a recording made with tail -format json.
+type dashboard struct {
+	positions  *ledgerx.PositionBook
+	collateral *ledgerx.CollateralTracker
+
+	mu        sync.Mutex
+	source    string
+	watch     map[int64]bool
+	labels    map[int64]string
+	books     map[int64]ledgerx.TopBookResponse
+	orders    map[string]*dashboardOrder
+	messages  int
+	updated   time.Time
+	connected bool
+	dirty     bool
+}
+
+func newDashboard(client *ledgerx.LedgerX, source string, watch []int64) *dashboard {
+	d := &dashboard{
+		positions:  ledgerx.NewPositionBook(client),
+		collateral: ledgerx.NewCollateralTracker(),
+		source:     source,
+		watch:      make(map[int64]bool),
+		labels:     make(map[int64]string),
+		books:      make(map[int64]ledgerx.TopBookResponse),
+		orders:     make(map[string]*dashboardOrder),
+		connected:  true,
+		dirty:      true,
+	}
+	for _, id := range watch {
+		d.watch[id] = true
+	}
+	return d
+}
+
+func (d *dashboard) setContracts(contracts []ledgerx.ListContractsData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range contracts {
+		d.labels[c.ID] = c.Label
+	}
+	d.positions.SetContracts(contracts)
+}
+
+func (d *dashboard) setOpenOrders(orders []ledgerx.ListOpenOrdersData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, o := range orders {
+		d.orders[o.Mid] = &dashboardOrder{
+			mid:        o.Mid,
+			contractID: o.ContractID,
+			side:       ledgerx.SideFromIsAsk(o.IsAsk),
+			price:      o.InsertedPrice,
+			size:       o.InsertedSize - o.FilledSize,
+			filled:     o.FilledSize,
+		}
+	}
+	d.dirty = true
+}
+
+func (d *dashboard) setConnected(connected bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.connected = connected
+	d.dirty = true
+}
+
+func (d *dashboard) HandleMessage(msg ledgerx.Message) {
+	d.positions.HandleMessage(msg)
+	d.collateral.HandleMessage(msg)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages++
+	d.updated = time.Now()
+	d.dirty = true
+
+	switch data := msg.Data.(type) {
+	case ledgerx.TopBookResponse:
+		if len(d.watch) == 0 || d.watch[data.ContractID] {
+			d.books[data.ContractID] = data
+		}
+	case ledgerx.ActionReportResponse:
+		d.applyReport(data)
+	}
+}
+
+func (d *dashboard) applyReport(report ledgerx.ActionReportResponse) {
+	switch report.StatusType {
+	case ledgerx.StatusCodeOrderInserted, ledgerx.StatusCodeTradeOccured, ledgerx.StatusCodeOrderCancelledAndReplaced:
+		if report.Size <= 0 {
+			delete(d.orders, report.MessageID)
+			return
+		}
+		order, ok := d.orders[report.MessageID]
+		if !ok {
+			order = &dashboardOrder{mid: report.MessageID, contractID: report.ContractID, side: ledgerx.SideFromIsAsk(report.IsAsk)}
+			d.orders[report.MessageID] = order
+		}
+		order.price = report.Price
+		order.size = report.Size
+		if report.StatusType == ledgerx.StatusCodeTradeOccured {
+			order.filled += report.FilledSize
+		}
+	default:
+		delete(d.orders, report.MessageID)
+	}
+}
+
+// takeDirty reports whether anything changed since the last call.
+func (d *dashboard) takeDirty() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dirty := d.dirty
+	d.dirty = false
+	return dirty
+}
+
+func (d *dashboard) label(contractID int64) string {
+	if label := d.labels[contractID]; label != "" {
+		return label
+	}
+	return strconv.FormatInt(contractID, 10)
+}
+
+// render draws the whole screen. With ansi unset it writes plain text,
+// for logs and tests.
+func (d *dashboard) render(w io.Writer, now time.Time, ansi bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	bold, reset := "", ""
+	if ansi {
+		fmt.Fprint(w, ansiClear)
+		bold, reset = ansiBold, ansiReset
+	}
+
+	status := ""
+	if d.source == "live" {
+		status = "connected  "
+		if !d.connected {
+			status = "DISCONNECTED  "
+		}
+	}
+	last := "-"
+	if !d.updated.IsZero() {
+		last = now.Sub(d.updated).Truncate(time.Millisecond).String() + " ago"
+	}
+	fmt.Fprintf(w, "%sLedgerX%s  %s  %smessages %d  last update %s  %s\n", bold, reset, d.source, status, d.messages, last, now.UTC().Format("15:04:05"))
+
+	section := func(title string) *tabwriter.Writer {
+		fmt.Fprintf(w, "\n%s%s%s\n", bold, title, reset)
+		return tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	}
+
+	tw := section("Top of book")
+	fmt.Fprintln(tw, "CONTRACT\tBID SIZE\tBID\tASK\tASK SIZE\tSPREAD\t")
+	books := make([]ledgerx.TopBookResponse, 0, len(d.books))
+	for _, book := range d.books {
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ContractID < books[j].ContractID })
+	if len(books) > maxBookRows {
+		books = books[:maxBookRows]
+	}
+	for _, b := range books {
+		spread := "-"
+		if b.Bid > 0 && b.Ask > 0 {
+			spread = cents(b.Ask - b.Bid)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t%s\t\n", d.label(b.ContractID), b.BidSize, cents(b.Bid), cents(b.Ask), b.AskSize, spread)
+	}
+	tw.Flush()
+
+	tw = section("Open orders")
+	fmt.Fprintln(tw, "CONTRACT\tSIDE\tPRICE\tOPEN\tFILLED\tMID\t")
+	orders := make([]*dashboardOrder, 0, len(d.orders))
+	for _, o := range d.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].contractID != orders[j].contractID {
+			return orders[i].contractID < orders[j].contractID
+		}
+		if orders[i].side != orders[j].side {
+			return orders[i].side < orders[j].side
+		}
+		return orders[i].mid < orders[j].mid
+	})
+	for _, o := range orders {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t\n", d.label(o.contractID), o.side, cents(o.price), o.size, o.filled, shortMid(o.mid))
+	}
+	tw.Flush()
+
+	tw = section("Positions")
+	fmt.Fprintln(tw, "CONTRACT\tSIZE\t")
+	positions := d.positions.Positions()
+	contractIDs := make([]int64, 0, len(positions))
+	for contractID, size := range positions {
+		if size != 0 {
+			contractIDs = append(contractIDs, contractID)
+		}
+	}
+	sort.Slice(contractIDs, func(i, j int) bool { return contractIDs[i] < contractIDs[j] })
+	for _, contractID := range contractIDs {
+		fmt.Fprintf(tw, "%s\t%d\t\n", d.label(contractID), positions[contractID])
+	}
+	tw.Flush()
+
+	tw = section("Collateral")
+	fmt.Fprintln(tw, "ASSET\tAVAILABLE\tLOCKED\tEQUITY\tUTILIZATION\t")
+	for _, s := range d.collateral.Summary() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.1f%%\t\n", s.Asset, s.FormattedAvailable, ledgerx.DefaultAssets.Format(s.Asset, s.Locked), s.FormattedEquity, s.Utilization*100)
+	}
+	return tw.Flush()
+}
+
+func shortMid(mid string) string {
+	if len(mid) > 12 {
+		return mid[:12]
+	}
+	return mid
+}
+
+func (c *cli) dashboard(args []string) error {
+	flags := c.flags("dashboard")
+	contracts := flags.String("contracts", "", "comma separated contract IDs to show (default all)")
+	replay := flags.String("replay", "", "replay a recording made with tail -format json instead of connecting")
+	interval := flags.Duration("interval", 50*time.Millisecond, "delay between replayed messages")
+	refresh := flags.Duration("refresh", 250*time.Millisecond, "screen refresh interval")
+	plain := flags.Bool("plain", false, "print frames without ANSI escape codes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var watch []int64
+	for _, s := range strings.Split(*contracts, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid contract ID %q", s)
+		}
+		watch = append(watch, id)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	source := "live"
+	if *replay != "" {
+		source = "replay " + *replay
+	}
+	d := newDashboard(c.client, source, watch)
+
+	done := make(chan error, 1)
+	if *replay != "" {
+		go func() { done <- replayRecording(ctx, *replay, *interval, d) }()
+	} else {
+		if err := c.startLive(d); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+			return d.render(c.stdout, time.Now(), !*plain)
+		case <-ticker.C:
+			if d.takeDirty() {
+				if err := d.render(c.stdout, time.Now(), !*plain); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// startLive seeds the dashboard from the REST API and subscribes it to
+// the websocket feed.
+func (c *cli) startLive(d *dashboard) error {
+	if contracts, err := c.listContracts(false); err == nil {
+		d.setContracts(contracts)
+	} else {
+		fmt.Fprintf(c.stderr, "ledgerx dashboard: listing contracts: %s\n", err)
+	}
+	if resp, err := c.client.ListOpenOrders(); err == nil {
+		d.setOpenOrders(resp.Data)
+	} else {
+		fmt.Fprintf(c.stderr, "ledgerx dashboard: listing open orders: %s\n", err)
+	}
+	if err := d.positions.Seed(); err != nil {
+		fmt.Fprintf(c.stderr, "ledgerx dashboard: loading positions: %s\n", err)
+	}
+
+	c.client.OnDisconnect(func() { d.setConnected(false) })
+	c.client.AddMessageHandler(d)
+	if err := c.client.Connect(); err != nil {
+		return err
+	}
+
+	// Drain the listen channel; the dashboard is fed as a message handler.
+	go func() {
+		for range c.client.Listen() {
+			d.setConnected(true)
+		}
+	}()
+	return nil
+}
+
+type recordedMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// replayRecording feeds each line of a tail -format json recording to h.
+func replayRecording(ctx context.Context, path string, interval time.Duration, h ledgerx.MessageHandler) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		msg, err := decodeRecorded(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if msg.Data == nil {
+			continue
+		}
+		h.HandleMessage(msg)
+
+		if interval > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// decodeRecorded decodes a recorded message. Types the dashboard does not
+// use decode to a message with nil Data.
+func decodeRecorded(line []byte) (ledgerx.Message, error) {
+	var recorded recordedMessage
+	if err := json.Unmarshal(line, &recorded); err != nil {
+		return ledgerx.Message{}, err
+	}
+
+	var data interface{}
+	var err error
+	switch recorded.Type {
+	case ledgerx.ChanBookTop:
+		var v ledgerx.TopBookResponse
+		err = json.Unmarshal(recorded.Data, &v)
+		data = v
+	case ledgerx.ChanActionReport:
+		var v ledgerx.ActionReportResponse
+		err = json.Unmarshal(recorded.Data, &v)
+		data = v
+	case ledgerx.ChanOpenPositionsUpdate:
+		var v ledgerx.OpenPositionsMessage
+		err = json.Unmarshal(recorded.Data, &v)
+		data = v
+	case ledgerx.ChanBalanceUpdate:
+		var v ledgerx.BalanceUpdateMessage
+		err = json.Unmarshal(recorded.Data, &v)
+		data = v
+	default:
+		return ledgerx.Message{Type: recorded.Type}, nil
+	}
+	if err != nil {
+		return ledgerx.Message{}, fmt.Errorf("decoding %s: %w", recorded.Type, err)
+	}
+	return ledgerx.Message{Type: recorded.Type, Data: data}, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+	"github.com/stretchr/testify/assert"
+)
+
+const recording = `{"type":"book_top","data":{"type":"book_top","contract_id":22220309,"ask":3520000,"ask_size":4,"bid":3510000,"bid_size":2,"clock":1}}
+{"type":"book_top","data":{"type":"book_top","contract_id":555,"ask":200,"ask_size":1,"bid":100,"bid_size":1,"clock":1}}
+{"type":"heartbeat","data":{"type":"heartbeat","timestamp":1,"ticks":1,"run_id":1,"interval_ms":1000}}
+{"type":"action_report","data":{"type":"action_report","contract_id":22220309,"mid":"aaaabbbbccccdddd","price":3500000,"size":5,"is_ask":false,"status_type":200}}
+{"type":"action_report","data":{"type":"action_report","contract_id":22220309,"mid":"aaaabbbbccccdddd","price":3500000,"size":3,"filled_size":2,"filled_price":3500000,"is_ask":false,"status_type":201}}
+{"type":"action_report","data":{"type":"action_report","contract_id":22220309,"mid":"eeee","price":3600000,"size":1,"is_ask":true,"status_type":200}}
+{"type":"action_report","data":{"type":"action_report","contract_id":22220309,"mid":"eeee","price":3600000,"size":0,"is_ask":true,"status_type":203}}
+{"type":"open_positions_update","data":{"type":"open_positions_update","positions":[{"contract_id":22220309,"size":7,"exercise_size":0}]}}
+{"type":"collateral_balance_update","data":{"collateral":{"available_balances":{"USD":150000},"order_locked_balances":{"USD":50000}}}}
+`
+
+func writeRecording(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "recording.ndjson")
+	if err := os.WriteFile(path, []byte(recording), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDashboardReplay(t *testing.T) {
+	c := newTestCLI(t)
+	out, stderr, code := c.run("dashboard", "-replay", writeRecording(t), "-interval", "0", "-plain", "-contracts", "22220309")
+	assert.Equal(t, 0, code, stderr)
+
+	frames := strings.Split(out, "LedgerX  replay")
+	frame := frames[len(frames)-1]
+	assert.NotContains(t, frame, "\x1b[")
+	assert.Contains(t, frame, "messages 8")
+
+	assert.Regexp(t, regexp.MustCompile(`22220309\s+2\s+35100\.00\s+35200\.00\s+4\s+100\.00`), frame)
+	assert.NotContains(t, frame, "555", "unwatched contracts are hidden")
+	assert.Regexp(t, regexp.MustCompile(`22220309\s+bid\s+35000\.00\s+3\s+2\s+aaaabbbbcccc\n`), frame)
+	assert.NotContains(t, frame, "eeee", "cancelled orders are removed")
+	assert.Regexp(t, regexp.MustCompile(`Positions\n\s*CONTRACT\s+SIZE\n\s*22220309\s+7\n`), frame)
+	assert.Regexp(t, regexp.MustCompile(`USD\s+1500\.00\s+500\.00\s+2000\.00\s+25\.0%`), frame)
+}
+
+func TestDashboardRenderANSI(t *testing.T) {
+	d := newDashboard(ledgerx.NewLedgerX("", "", "", ""), "live", nil)
+	d.setContracts([]ledgerx.ListContractsData{{ID: 1, Label: "BTC-Mini-02JUN2021-NextDay"}})
+	d.HandleMessage(ledgerx.Message{Type: ledgerx.ChanBookTop, Data: ledgerx.TopBookResponse{ContractID: 1, Bid: 100, Ask: 0}})
+	d.setConnected(false)
+
+	var buf bytes.Buffer
+	assert.Nil(t, d.render(&buf, time.Now(), true))
+	assert.True(t, strings.HasPrefix(buf.String(), ansiClear))
+	assert.Contains(t, buf.String(), "DISCONNECTED")
+	assert.Regexp(t, `BTC-Mini-02JUN2021-NextDay\s+0\s+1\.00\s+0\.00\s+0\s+-`, buf.String())
+
+	assert.True(t, d.takeDirty())
+	assert.False(t, d.takeDirty())
+}
+
+func TestDecodeRecordedErrors(t *testing.T) {
+	_, err := decodeRecorded([]byte(`not json`))
+	assert.NotNil(t, err)
+	_, err = decodeRecorded([]byte(`{"type":"book_top","data":{"contract_id":"x"}}`))
+	assert.NotNil(t, err)
+
+	msg, err := decodeRecorded([]byte(`{"type":"meta","data":{}}`))
+	assert.Nil(t, err)
+	assert.Nil(t, msg.Data)
+}
@@ -0,0 +1,185 @@
+// Command ledgerx is a command-line client for the LedgerX API.
+//
+// Usage:
+//
+//	ledgerx [-format table|json] [-config path] [-env prod|staging] <command> [flags]
+//
+// The API key is read from LEDGERX_API_KEY or the api_key field of the
+// config file, by default ~/.config/ledgerx/config.json.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/payaaam/go-ledgerx"
+)
+
+type config struct {
+	APIKey       string `json:"api_key"`
+	Environment  string `json:"environment"`
+	WebsocketURL string `json:"websocket_url"`
+	RestURL      string `json:"rest_url"`
+	TradingURL   string `json:"trading_url"`
+}
+
+type cli struct {
+	client *ledgerx.LedgerX
+	out    *printer
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"contracts":  {"list contracts", (*cli).contracts},
+	"orders":     {"list open orders", (*cli).orders},
+	"buy":        {"place a bid", (*cli).buy},
+	"sell":       {"place an ask", (*cli).sell},
+	"cancel":     {"cancel an order", (*cli).cancel},
+	"replace":    {"cancel and replace an order", (*cli).replace},
+	"cancel-all": {"cancel all open orders", (*cli).cancelAll},
+	"positions":  {"list positions", (*cli).positions},
+	"balances":   {"show collateral balances", (*cli).balances},
+	"tail":       {"stream websocket messages", (*cli).tail},
+	"dashboard":  {"live terminal dashboard", (*cli).dashboard},
+	"export":     {"export trade history", (*cli).export},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer, getenv func(string) string) int {
+	flags := flag.NewFlagSet("ledgerx", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "table", "output format: table or json")
+	configPath := flags.String("config", defaultConfigPath(getenv), "config file")
+	env := flags.String("env", "", "environment: prod or staging (default from config or LEDGERX_ENV)")
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	name := flags.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "ledgerx: unknown command %q\n", name)
+		flags.Usage()
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "ledgerx: unknown format %q\n", *format)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, *env, getenv)
+	if err != nil {
+		fmt.Fprintf(stderr, "ledgerx: %s\n", err)
+		return 1
+	}
+
+	c := &cli{
+		client: ledgerx.NewLedgerX(cfg.WebsocketURL, cfg.RestURL, cfg.TradingURL, cfg.APIKey),
+		out:    &printer{w: stdout, json: *format == "json"},
+		stdout: stdout,
+		stderr: stderr,
+	}
+	if err := cmd.run(c, flags.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "ledgerx %s: %s\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintln(w, "usage: ledgerx [flags] <command> [command flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	flags.PrintDefaults()
+}
+
+func defaultConfigPath(getenv func(string) string) string {
+	if path := getenv("LEDGERX_CONFIG"); path != "" {
+		return path
+	}
+	home := getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", "ledgerx", "config.json")
+}
+
+// loadConfig reads the config file, if present, and applies environment
+// overrides. URLs not set explicitly come from the environment's defaults.
+func loadConfig(path string, env string, getenv func(string) string) (config, error) {
+	var cfg config
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return cfg, fmt.Errorf("reading config %s: %w", path, err)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return cfg, err
+		}
+	}
+
+	if key := getenv("LEDGERX_API_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+	if env == "" {
+		env = getenv("LEDGERX_ENV")
+	}
+	if env != "" {
+		cfg.Environment = env
+	}
+
+	var ws, rest, trading string
+	switch strings.ToLower(cfg.Environment) {
+	case "", "prod", "production":
+		ws, rest, trading = ledgerx.ProdWebSocketBaseURL, ledgerx.ProdRestBaseURL, ledgerx.ProdTradingBaseURL
+	case "staging":
+		ws, rest, trading = ledgerx.StagingWebSocketBaseURL, ledgerx.StagingRestBaseURL, ledgerx.StagingTradingBaseURL
+	default:
+		return cfg, fmt.Errorf("unknown environment %q", cfg.Environment)
+	}
+	if cfg.WebsocketURL == "" {
+		cfg.WebsocketURL = ws
+	}
+	if cfg.RestURL == "" {
+		cfg.RestURL = rest
+	}
+	if cfg.TradingURL == "" {
+		cfg.TradingURL = trading
+	}
+	return cfg, nil
+}
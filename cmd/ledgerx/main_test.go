@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+	"github.com/payaaam/go-ledgerx/ledgerxtest"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCLI struct {
+	t    *testing.T
+	mock *ledgerxtest.MockExchange
+	dir  string
+	env  map[string]string
+}
+
+func newTestCLI(t *testing.T) *testCLI {
+	mock := ledgerxtest.NewMockExchange()
+	t.Cleanup(mock.Close)
+
+	dir := t.TempDir()
+	cfg, _ := json.Marshal(config{
+		APIKey:       "from-config",
+		WebsocketURL: mock.WebsocketURL(),
+		RestURL:      mock.URL(),
+		TradingURL:   mock.URL(),
+	})
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, cfg, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &testCLI{t: t, mock: mock, dir: dir, env: map[string]string{"LEDGERX_CONFIG": path}}
+}
+
+func (c *testCLI) run(args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr, func(key string) string { return c.env[key] })
+	return stdout.String(), stderr.String(), code
+}
+
+// publishWhenConnected publishes v once the next command connects.
+func (c *testCLI) publishWhenConnected(v interface{}) {
+	connected := c.mock.Connections()
+	go func() {
+		for c.mock.Connections() <= connected {
+			time.Sleep(5 * time.Millisecond)
+		}
+		c.mock.Publish(v)
+	}()
+}
+
+func TestOrderCommands(t *testing.T) {
+	c := newTestCLI(t)
+
+	out, stderr, code := c.run("buy", "-contract", "7", "-size", "2", "-price", "150000")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, "MID")
+	_, _, code = c.run("sell", "-contract", "7", "-size", "1", "-price", "160000")
+	assert.Equal(t, 0, code)
+
+	out, _, code = c.run("orders")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Regexp(t, `^MID\s+CONTRACT\s+SIDE\s+PRICE\s+OPEN\s+FILLED\s+TYPE$`, lines[0])
+		assert.Regexp(t, `\s7\s+bid\s+1500\.00\s+2\s+0\s+limit$`, lines[1])
+	}
+
+	orders := c.mock.OpenOrders()
+	_, stderr, code = c.run("replace", "-mid", orders[0].Mid, "-contract", "7", "-size", "3", "-price", "151000")
+	assert.Equal(t, 0, code, stderr)
+
+	out, _, code = c.run("-format", "json", "orders")
+	assert.Equal(t, 0, code)
+	var listed []ledgerx.ListOpenOrdersData
+	assert.Nil(t, json.Unmarshal([]byte(out), &listed))
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, int64(151000), listed[0].InsertedPrice)
+	}
+
+	_, _, code = c.run("cancel", "-mid", orders[1].Mid, "-contract", "7")
+	assert.Equal(t, 0, code)
+	assert.Len(t, c.mock.OpenOrders(), 1)
+
+	_, _, code = c.run("cancel-all")
+	assert.Equal(t, 0, code)
+	assert.Len(t, c.mock.OpenOrders(), 0)
+
+	_, stderr, code = c.run("buy", "-contract", "7")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "-contract and -size are required")
+}
+
+func TestContractsAndPositions(t *testing.T) {
+	c := newTestCLI(t)
+	c.mock.SetContracts([]ledgerx.ListContractsData{
+		{ID: 1, Label: "BTC-Mini-02JUN2021-NextDay", UnderlyingAsset: "CBTC", DerivativeType: "day_ahead_swap", Active: true},
+		{ID: 2, Label: "ETH-02JUN2021-NextDay", UnderlyingAsset: "ETH", DerivativeType: "day_ahead_swap", Active: true},
+		{ID: 3, Label: "BTC-25JUN2021-35000-Call", UnderlyingAsset: "CBTC", DerivativeType: "options_contract", Active: true},
+		{ID: 4, Label: "BTC-24SEP2021-Future", UnderlyingAsset: "CBTC", DerivativeType: "future_contract", Active: true},
+	})
+	c.mock.SetPositions([]ledgerx.ListPositionsData{
+		{Type: "short", Size: 4, Contract: ledgerx.ListContractsData{ID: 1, Label: "BTC-Mini-02JUN2021-NextDay"}},
+		{Type: "long", Size: 0, Contract: ledgerx.ListContractsData{ID: 2}},
+	})
+
+	out, _, code := c.run("-format", "json", "contracts", "-asset", "eth")
+	assert.Equal(t, 0, code)
+	var contracts []ledgerx.ListContractsData
+	assert.Nil(t, json.Unmarshal([]byte(out), &contracts))
+	if assert.Len(t, contracts, 1) {
+		assert.Equal(t, int64(2), contracts[0].ID)
+	}
+
+	out, _, code = c.run("contracts")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "BTC-25JUN2021-35000-Call", "options should be listed")
+	assert.Contains(t, out, "BTC-24SEP2021-Future", "futures should be listed")
+
+	out, _, code = c.run("positions")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Regexp(t, `^1\s+BTC-Mini-02JUN2021-NextDay\s+-4\s+0\s+0$`, lines[1])
+	}
+}
+
+func TestStreamingCommands(t *testing.T) {
+	c := newTestCLI(t)
+
+	c.publishWhenConnected(ledgerx.TopBookResponse{Type: ledgerx.ChanBookTop, ContractID: 9, Bid: 100, Ask: 200})
+	out, stderr, code := c.run("-format", "json", "tail", "-types", "book_top", "-n", "1")
+	assert.Equal(t, 0, code, stderr)
+	assert.True(t, strings.HasPrefix(out, `{"type":"book_top","data":{`), out)
+	assert.Contains(t, out, `"contract_id":9`)
+
+	c.publishWhenConnected(map[string]interface{}{
+		"type": ledgerx.ChanBalanceUpdate,
+		"collateral": map[string]interface{}{
+			"available_balances":    map[string]int64{"USD": 150000, "BTC": 100000000},
+			"order_locked_balances": map[string]int64{"USD": 50000},
+		},
+	})
+	out, stderr, code = c.run("balances", "-timeout", "5s")
+	assert.Equal(t, 0, code, stderr)
+	assert.Regexp(t, `USD\s+1500\.00\s+500\.00\s+2000\.00\s+25\.0%`, out)
+	assert.Regexp(t, `BTC\s+1\.00000000`, out)
+}
+
+func TestExportCommandResumes(t *testing.T) {
+	c := newTestCLI(t)
+	c.mock.SetTrades([]ledgerx.ListTradeData{
+		{ID: 1, ContractID: 1, ContractLabel: "BTC-Mini-02JUN2021-NextDay", FilledPrice: 3500000, FilledSize: 1, Side: "bid", Timestamp: ledgerx.TradeTimeOf(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC))},
+		{ID: 2, ContractID: 1, ContractLabel: "BTC-Mini-02JUN2021-NextDay", FilledPrice: 3510000, FilledSize: 1, Side: "ask", Timestamp: ledgerx.TradeTimeOf(time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC))},
+	})
+
+	out := filepath.Join(c.dir, "trades.csv")
+	state := filepath.Join(c.dir, "trades.state")
+	args := []string{"export", "-from", "2021-06-01", "-to", "2021-07-01", "-types", "day_ahead_swap", "-out", out, "-state", state}
+	_, stderr, code := c.run(args...)
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "exported 2 trades, last trade ID 2")
+
+	_, stderr, code = c.run(args...)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, "exported 0 trades, last trade ID 2")
+
+	data, _ := os.ReadFile(out)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 3)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{"api_key": "file-key", "environment": "staging"}`), 0600)
+
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	cfg, err := loadConfig(path, "", getenv)
+	assert.Nil(t, err)
+	assert.Equal(t, "file-key", cfg.APIKey)
+	assert.Equal(t, ledgerx.StagingTradingBaseURL, cfg.TradingURL)
+
+	env["LEDGERX_API_KEY"] = "env-key"
+	cfg, _ = loadConfig(path, "prod", getenv)
+	assert.Equal(t, "env-key", cfg.APIKey)
+	assert.Equal(t, ledgerx.ProdTradingBaseURL, cfg.TradingURL)
+
+	cfg, err = loadConfig(filepath.Join(dir, "missing.json"), "", getenv)
+	assert.Nil(t, err)
+	assert.Equal(t, ledgerx.ProdRestBaseURL, cfg.RestURL)
+
+	_, err = loadConfig(path, "moon", getenv)
+	assert.NotNil(t, err)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	c := newTestCLI(t)
+	_, stderr, code := c.run("frobnicate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "frobnicate"`)
+	assert.Contains(t, stderr, "cancel-all")
+}
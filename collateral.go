@@ -0,0 +1,186 @@
+package ledgerx
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LowBalanceAlert is passed to low balance callbacks when the available
+// balance of an asset falls below a threshold.
+type LowBalanceAlert struct {
+	Asset     string
+	Threshold int64
+	Available int64
+	Equity    int64
+}
+
+type balanceAlert struct {
+	asset     string
+	threshold int64
+	fn        func(LowBalanceAlert)
+	triggered bool
+}
+
+// CollateralTracker keeps the latest collateral_balance_update and derives
+// equity and utilization per asset. Low balance callbacks fire once when
+// available collateral drops below their threshold and re-arm when it
+// recovers.
+type CollateralTracker struct {
+	mu         sync.RWMutex
+	collateral Collateral
+	updated    time.Time
+	alerts     []*balanceAlert
+}
+
+func NewCollateralTracker() *CollateralTracker {
+	return &CollateralTracker{}
+}
+
+// OnLowBalance calls fn when the available balance of asset drops below
+// threshold, in the asset's smallest unit.
+func (c *CollateralTracker) OnLowBalance(asset string, threshold int64, fn func(LowBalanceAlert)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alerts = append(c.alerts, &balanceAlert{asset: asset, threshold: threshold, fn: fn})
+}
+
+func (c *CollateralTracker) HandleMessage(msg Message) {
+	update, ok := msg.Data.(BalanceUpdateMessage)
+	if !ok {
+		return
+	}
+	c.Update(update.Collateral)
+}
+
+// Update records a new collateral snapshot and evaluates alerts.
+func (c *CollateralTracker) Update(collateral Collateral) {
+	c.mu.Lock()
+	c.collateral = collateral
+	c.updated = time.Now()
+
+	var fired []func()
+	for _, alert := range c.alerts {
+		available := collateral.AvailableBalances.Get(alert.asset)
+		if available >= alert.threshold {
+			alert.triggered = false
+			continue
+		}
+		if alert.triggered {
+			continue
+		}
+		alert.triggered = true
+		fn, event := alert.fn, LowBalanceAlert{
+			Asset:     alert.asset,
+			Threshold: alert.threshold,
+			Available: available,
+			Equity:    c.equityLocked(alert.asset),
+		}
+		fired = append(fired, func() { fn(event) })
+	}
+	c.mu.Unlock()
+
+	for _, fn := range fired {
+		fn()
+	}
+}
+
+// Snapshot returns the latest collateral and when it was received. The
+// time is zero if no update has arrived yet.
+func (c *CollateralTracker) Snapshot() (Collateral, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.collateral, c.updated
+}
+
+func (c *CollateralTracker) Available(asset string) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.collateral.AvailableBalances.Get(asset)
+}
+
+// Locked returns the balance of asset held against deliveries, fees,
+// resting orders and positions.
+func (c *CollateralTracker) Locked(asset string) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lockedLocked(asset)
+}
+
+// TotalEquity returns the available and locked balances of asset combined.
+func (c *CollateralTracker) TotalEquity(asset string) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.equityLocked(asset)
+}
+
+// Utilization returns the locked fraction of the asset's total equity, or
+// zero when there is no equity.
+func (c *CollateralTracker) Utilization(asset string) float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	equity := c.equityLocked(asset)
+	if equity == 0 {
+		return 0
+	}
+	return float64(c.lockedLocked(asset)) / float64(equity)
+}
+
+// AssetSummary is the collateral breakdown of a single asset. Formatted
+// amounts are in whole units according to DefaultAssets.
+type AssetSummary struct {
+	Asset              string  `json:"asset"`
+	Available          int64   `json:"available"`
+	Locked             int64   `json:"locked"`
+	Equity             int64   `json:"equity"`
+	Utilization        float64 `json:"utilization"`
+	FormattedAvailable string  `json:"formatted_available"`
+	FormattedEquity    string  `json:"formatted_equity"`
+}
+
+// Summary returns a breakdown of every asset present in any balance of
+// the latest snapshot, sorted by asset.
+func (c *CollateralTracker) Summary() []AssetSummary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, balance := range c.collateral.balances() {
+		for _, asset := range balance.Assets() {
+			seen[asset] = true
+		}
+	}
+	assets := make([]string, 0, len(seen))
+	for asset := range seen {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	summaries := make([]AssetSummary, 0, len(assets))
+	for _, asset := range assets {
+		summary := AssetSummary{
+			Asset:     asset,
+			Available: c.collateral.AvailableBalances.Get(asset),
+			Locked:    c.lockedLocked(asset),
+			Equity:    c.equityLocked(asset),
+		}
+		if summary.Equity != 0 {
+			summary.Utilization = float64(summary.Locked) / float64(summary.Equity)
+		}
+		summary.FormattedAvailable = DefaultAssets.Format(asset, summary.Available)
+		summary.FormattedEquity = DefaultAssets.Format(asset, summary.Equity)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func (c *CollateralTracker) lockedLocked(asset string) int64 {
+	return c.collateral.DeliverableLockedBalances.Get(asset) +
+		c.collateral.FeeLockedBalances.Get(asset) +
+		c.collateral.OrderLockedBalances.Get(asset) +
+		c.collateral.PositionLockedBalances.Get(asset)
+}
+
+func (c *CollateralTracker) equityLocked(asset string) int64 {
+	return c.collateral.AvailableBalances.Get(asset) + c.lockedLocked(asset)
+}
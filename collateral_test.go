@@ -0,0 +1,52 @@
+package ledgerx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollateralTrackerEquityAndUtilization(t *testing.T) {
+	tracker := NewCollateralTracker()
+	tracker.HandleMessage(Message{Type: ChanBalanceUpdate, Data: BalanceUpdateMessage{
+		Collateral: Collateral{
+			AvailableBalances:         LedgerBalance{USD: 6000, CBTC: 100},
+			DeliverableLockedBalances: LedgerBalance{USD: 500},
+			FeeLockedBalances:         LedgerBalance{USD: 500},
+			OrderLockedBalances:       LedgerBalance{USD: 1000},
+			PositionLockedBalances:    LedgerBalance{USD: 2000},
+		},
+	}})
+
+	_, updated := tracker.Snapshot()
+	assert.False(t, updated.IsZero(), "should record update time")
+	assert.Equal(t, int64(10000), tracker.TotalEquity("USD"))
+	assert.Equal(t, int64(4000), tracker.Locked("USD"))
+	assert.Equal(t, 0.4, tracker.Utilization("USD"))
+	assert.Equal(t, 0.0, tracker.Utilization("CBTC"))
+	assert.Equal(t, 0.0, tracker.Utilization("ETH"), "should handle assets without equity")
+}
+
+func TestCollateralTrackerLowBalanceAlerts(t *testing.T) {
+	tracker := NewCollateralTracker()
+	var alerts []LowBalanceAlert
+	tracker.OnLowBalance("USD", 1000, func(alert LowBalanceAlert) {
+		alerts = append(alerts, alert)
+	})
+
+	update := func(available int64) {
+		tracker.Update(Collateral{
+			AvailableBalances:   LedgerBalance{USD: available},
+			OrderLockedBalances: LedgerBalance{USD: 200},
+		})
+	}
+
+	update(5000)
+	assert.Empty(t, alerts, "should not alert above threshold")
+	update(900)
+	update(800)
+	assert.Equal(t, []LowBalanceAlert{{Asset: "USD", Threshold: 1000, Available: 900, Equity: 1100}}, alerts, "should alert once per crossing")
+	update(1500)
+	update(100)
+	assert.Len(t, alerts, 2, "should re-arm after recovering")
+}
@@ -23,10 +23,13 @@ const (
 )
 
 // Contract IDs
+//
+// Deprecated: contract IDs change with every listing, use ContractRegistry.
 const (
 	BtcUsdPair = 22220309
 )
 
+// Deprecated: use ContractRegistry.PairName.
 var ContractIDToPairs = map[int]string{
 	22220309: "XBT/USD",
 }
@@ -36,6 +39,9 @@ const (
 	DefaultPageSize      = 100
 )
 
+// DerivativeTypes are the contract types listed by the exchange.
+var DerivativeTypes = []string{"day_ahead_swap", "options_contract", "future_contract"}
+
 const (
 	StatusCodeOrderInserted             = 200
 	StatusCodeTradeOccured              = 201
@@ -0,0 +1,319 @@
+package ledgerx
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContractLookup resolves contract metadata by ID.
+type ContractLookup interface {
+	Contract(id int64) (ListContractsData, bool)
+}
+
+type ContractEventType string
+
+const (
+	ContractListed  ContractEventType = "listed"
+	ContractExpired ContractEventType = "expired"
+)
+
+type ContractEvent struct {
+	Type     ContractEventType
+	Contract ListContractsData
+}
+
+// ContractRegistry maps contract IDs to their metadata, label and pair
+// name. It is populated from the contracts endpoint, and each refresh emits
+// events for contracts that were newly listed or have expired. Expired
+// contracts stay queryable through Contract and ByLabel, so that positions
+// in them can still be settled, until they are dropped with Prune. It is
+// safe for concurrent use.
+type ContractRegistry struct {
+	fetch func() ([]ListContractsData, error)
+	now   func() time.Time
+
+	mu          sync.RWMutex
+	contracts   map[int64]ListContractsData
+	expired     map[int64]bool
+	byLabel     map[string]int64
+	subscribers []func(ContractEvent)
+}
+
+// NewContractRegistry creates a registry populated with the active
+// contracts of every derivative type, paging through
+// client.ListActiveContracts.
+func NewContractRegistry(client *LedgerX) *ContractRegistry {
+	return NewContractRegistryWithFetcher(func() ([]ListContractsData, error) {
+		var contracts []ListContractsData
+		for _, derivativeType := range DerivativeTypes {
+			var offset int32
+			for {
+				resp, err := client.ListActiveContracts(derivativeType, offset)
+				if err != nil {
+					return nil, err
+				}
+				contracts = append(contracts, resp.Data...)
+				offset += int32(len(resp.Data))
+				if len(resp.Data) < DefaultPageSize {
+					break
+				}
+			}
+		}
+		return contracts, nil
+	})
+}
+
+// NewContractRegistryWithFetcher creates a registry populated by fetch,
+// for callers that list contracts with other filters.
+func NewContractRegistryWithFetcher(fetch func() ([]ListContractsData, error)) *ContractRegistry {
+	return &ContractRegistry{
+		fetch:     fetch,
+		now:       time.Now,
+		contracts: make(map[int64]ListContractsData),
+		expired:   make(map[int64]bool),
+		byLabel:   make(map[string]int64),
+	}
+}
+
+// Subscribe registers fn to receive listing and expiry events.
+func (r *ContractRegistry) Subscribe(fn func(ContractEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Refresh fetches the current contracts and updates the registry. Contracts
+// that are no longer listed, or whose expiry has passed, are marked expired.
+func (r *ContractRegistry) Refresh() error {
+	fetched, err := r.fetch()
+	if err != nil {
+		return fmt.Errorf("Error refreshing contract registry: %w", err)
+	}
+
+	now := r.now()
+	live := make(map[int64]ListContractsData, len(fetched))
+	r.mu.Lock()
+	for _, c := range fetched {
+		if c.DateExpires.IsZero() || c.DateExpires.After(now) {
+			live[c.ID] = c
+		} else if _, known := r.contracts[c.ID]; !known {
+			// First seen after expiry: keep it for lookups, without events.
+			r.contracts[c.ID] = c
+			r.expired[c.ID] = true
+			r.byLabel[c.Label] = c.ID
+		}
+	}
+
+	var events []ContractEvent
+	for id, c := range r.contracts {
+		if _, ok := live[id]; !ok && !r.expired[id] {
+			r.expired[id] = true
+			events = append(events, ContractEvent{Type: ContractExpired, Contract: c})
+		}
+	}
+	for id, c := range live {
+		if _, ok := r.contracts[id]; !ok || r.expired[id] {
+			events = append(events, ContractEvent{Type: ContractListed, Contract: c})
+		}
+		delete(r.expired, id)
+		r.contracts[id] = c
+		r.byLabel[c.Label] = id
+	}
+	subscribers := r.subscribers
+	r.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Type != events[j].Type {
+			return events[i].Type == ContractExpired
+		}
+		return events[i].Contract.ID < events[j].Contract.ID
+	})
+	for _, event := range events {
+		for _, fn := range subscribers {
+			fn(event)
+		}
+	}
+	return nil
+}
+
+// Prune drops expired contracts whose expiry is before the given time, or
+// that were delisted without an expiry, returning how many were dropped.
+// Only prune contracts in which no positions remain open.
+func (r *ContractRegistry) Prune(expiredBefore time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pruned := 0
+	for id := range r.expired {
+		c := r.contracts[id]
+		if c.DateExpires.IsZero() || c.DateExpires.Before(expiredBefore) {
+			delete(r.contracts, id)
+			delete(r.expired, id)
+			if r.byLabel[c.Label] == id {
+				delete(r.byLabel, c.Label)
+			}
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Expired reports whether a known contract has expired or been delisted.
+func (r *ContractRegistry) Expired(id int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.expired[id]
+}
+
+// Start refreshes the registry every interval until the returned stop
+// function is called. Refresh errors are passed to onError, which may be nil.
+func (r *ContractRegistry) Start(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := r.Refresh(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (r *ContractRegistry) Contract(id int64) (ListContractsData, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.contracts[id]
+	return c, ok
+}
+
+func (r *ContractRegistry) ByLabel(label string) (ListContractsData, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.byLabel[label]
+	if !ok {
+		return ListContractsData{}, false
+	}
+	return r.contracts[id], true
+}
+
+// Label returns the exchange label of a contract, or "" if unknown.
+func (r *ContractRegistry) Label(id int64) string {
+	c, _ := r.Contract(id)
+	return c.Label
+}
+
+// PairName returns the trading pair of a contract, e.g. "XBT/USD", or ""
+// if unknown.
+func (r *ContractRegistry) PairName(id int64) string {
+	c, ok := r.Contract(id)
+	if !ok {
+		return ""
+	}
+	return PairName(c)
+}
+
+// Contracts returns all live contracts sorted by ID.
+func (r *ContractRegistry) Contracts() []ListContractsData {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	contracts := make([]ListContractsData, 0, len(r.contracts))
+	for id, c := range r.contracts {
+		if !r.expired[id] {
+			contracts = append(contracts, c)
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].ID < contracts[j].ID })
+	return contracts
+}
+
+var pairSymbols = map[string]string{
+	AssetBTC:  "XBT",
+	AssetCBTC: "XBT",
+}
+
+// PairName returns the trading pair of a contract's underlying and
+// collateral assets, using XBT for bitcoin as in ContractIDToPairs.
+func PairName(c ListContractsData) string {
+	underlying := c.UnderlyingAsset
+	if symbol, ok := pairSymbols[underlying]; ok {
+		underlying = symbol
+	}
+	collateral := c.CollateralAsset
+	if collateral == "" {
+		collateral = AssetUSD
+	}
+	return underlying + "/" + collateral
+}
+
+// Contract kinds parsed from labels.
+const (
+	ContractKindNextDay = "next_day"
+	ContractKindFuture  = "future"
+	ContractKindCall    = "call"
+	ContractKindPut     = "put"
+)
+
+// ContractLabel is the parsed form of an exchange label such as
+// "BTC-Mini-25JUN2021-35000-Call" or "ETH-02JUN2021-NextDay".
+type ContractLabel struct {
+	Asset  string
+	Mini   bool
+	Expiry time.Time // date only, UTC
+	Strike int64     // dollars, options only
+	Kind   string
+}
+
+// ParseContractLabel parses an exchange contract label.
+func ParseContractLabel(label string) (ContractLabel, error) {
+	parts := strings.Split(label, "-")
+	if len(parts) < 3 {
+		return ContractLabel{}, fmt.Errorf("invalid contract label %q", label)
+	}
+
+	parsed := ContractLabel{Asset: parts[0]}
+	parts = parts[1:]
+	if strings.EqualFold(parts[0], "Mini") {
+		parsed.Mini = true
+		parts = parts[1:]
+	}
+	if len(parts) < 2 {
+		return ContractLabel{}, fmt.Errorf("invalid contract label %q", label)
+	}
+
+	expiry, err := time.Parse("02Jan2006", parts[0])
+	if err != nil {
+		return ContractLabel{}, fmt.Errorf("invalid contract label %q: bad date %q", label, parts[0])
+	}
+	parsed.Expiry = expiry
+
+	switch {
+	case len(parts) == 2 && strings.EqualFold(parts[1], "NextDay"):
+		parsed.Kind = ContractKindNextDay
+	case len(parts) == 2 && strings.EqualFold(parts[1], "Future"):
+		parsed.Kind = ContractKindFuture
+	case len(parts) == 3 && (strings.EqualFold(parts[2], "Call") || strings.EqualFold(parts[2], "Put")):
+		strike, err := strconv.ParseInt(strings.ReplaceAll(parts[1], ",", ""), 10, 64)
+		if err != nil {
+			return ContractLabel{}, fmt.Errorf("invalid contract label %q: bad strike %q", label, parts[1])
+		}
+		parsed.Strike = strike
+		parsed.Kind = strings.ToLower(parts[2])
+	default:
+		return ContractLabel{}, fmt.Errorf("invalid contract label %q", label)
+	}
+	return parsed, nil
+}
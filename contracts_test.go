@@ -0,0 +1,154 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractRegistryRefreshEvents(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	swap := ListContractsData{
+		ID:              22220309,
+		Label:           "BTC-Mini-02JUN2021-NextDay",
+		UnderlyingAsset: "CBTC",
+		CollateralAsset: "USD",
+		DateExpires:     LedgerTime{now.Add(24 * time.Hour)},
+	}
+	option := ListContractsData{
+		ID:              22220400,
+		Label:           "ETH-25JUN2021-2500-Call",
+		UnderlyingAsset: "ETH",
+		CollateralAsset: "USD",
+		DateExpires:     LedgerTime{now.Add(48 * time.Hour)},
+	}
+
+	listed := []ListContractsData{swap}
+	registry := NewContractRegistryWithFetcher(func() ([]ListContractsData, error) {
+		return listed, nil
+	})
+	registry.now = func() time.Time { return now }
+
+	var events []ContractEvent
+	registry.Subscribe(func(event ContractEvent) {
+		events = append(events, event)
+	})
+
+	assert.Nil(t, registry.Refresh())
+	assert.Equal(t, []ContractEvent{{Type: ContractListed, Contract: swap}}, events)
+	assert.Equal(t, "XBT/USD", registry.PairName(22220309), "should match the legacy pair name")
+	assert.Equal(t, ContractIDToPairs[BtcUsdPair], registry.PairName(BtcUsdPair))
+	assert.Equal(t, swap.Label, registry.Label(22220309))
+	c, ok := registry.ByLabel(swap.Label)
+	assert.True(t, ok)
+	assert.Equal(t, swap.ID, c.ID)
+
+	events = nil
+	listed = []ListContractsData{swap, option}
+	now = now.Add(36 * time.Hour)
+	assert.Nil(t, registry.Refresh())
+	assert.Equal(t, []ContractEvent{
+		{Type: ContractExpired, Contract: swap},
+		{Type: ContractListed, Contract: option},
+	}, events, "should expire contracts past their expiry even if still returned")
+	assert.Equal(t, []ListContractsData{option}, registry.Contracts())
+	c, ok = registry.Contract(swap.ID)
+	assert.True(t, ok, "expired contracts should stay queryable for settlement")
+	assert.Equal(t, swap, c)
+	assert.True(t, registry.Expired(swap.ID))
+	assert.False(t, registry.Expired(option.ID))
+	assert.Equal(t, "ETH/USD", registry.PairName(option.ID))
+
+	events = nil
+	listed = []ListContractsData{option}
+	assert.Nil(t, registry.Refresh())
+	assert.Empty(t, events, "expiry should only be reported once")
+
+	assert.Equal(t, 0, registry.Prune(swap.DateExpires.Time))
+	assert.Equal(t, 1, registry.Prune(now))
+	_, ok = registry.Contract(swap.ID)
+	assert.False(t, ok)
+	_, ok = registry.ByLabel(swap.Label)
+	assert.False(t, ok)
+}
+
+func TestContractRegistryListsEveryDerivativeType(t *testing.T) {
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		query := r.URL.Query()
+		var data []ListContractsData
+		switch query.Get("derivative_type") {
+		case "day_ahead_swap":
+			data = []ListContractsData{{ID: 1, DerivativeType: "day_ahead_swap"}}
+		case "options_contract":
+			// A full first page forces a second request.
+			if query.Get("offset") == "0" {
+				for i := 0; i < DefaultPageSize; i++ {
+					data = append(data, ListContractsData{ID: int64(100 + i), DerivativeType: "options_contract"})
+				}
+			} else {
+				data = []ListContractsData{{ID: 999, DerivativeType: "options_contract"}}
+			}
+		}
+		json.NewEncoder(w).Encode(&ListContractsResponse{Data: data})
+	}))
+	defer s.Close()
+
+	registry := NewContractRegistry(NewLedgerX("", s.URL, s.URL, ""))
+	assert.Nil(t, registry.Refresh())
+	assert.Len(t, registry.Contracts(), DefaultPageSize+2)
+	_, ok := registry.Contract(999)
+	assert.True(t, ok, "should page through options")
+	assert.Len(t, queries, 4)
+	for _, q := range queries {
+		assert.Contains(t, q, "active=true")
+	}
+}
+
+func TestContractRegistryRefreshError(t *testing.T) {
+	registry := NewContractRegistryWithFetcher(func() ([]ListContractsData, error) {
+		return nil, errors.New("boom")
+	})
+	assert.NotNil(t, registry.Refresh())
+	assert.Empty(t, registry.Contracts())
+}
+
+func TestRiskManagerUsesContractLookup(t *testing.T) {
+	registry := NewContractRegistryWithFetcher(func() ([]ListContractsData, error) {
+		return []ListContractsData{{ID: 1, UnderlyingAsset: "CBTC"}, {ID: 2, UnderlyingAsset: "CBTC"}}, nil
+	})
+	assert.Nil(t, registry.Refresh())
+
+	risk := NewRiskManager(RiskLimits{MaxNetPosition: 5})
+	risk.SetContractLookup(registry)
+	risk.HandleMessage(Message{Type: ChanOpenPositionsUpdate, Data: OpenPositionsMessage{
+		Positions: []Position{{ContractID: 1, Size: 4}},
+	}})
+
+	assert.NotNil(t, risk.CheckOrder(&CreateOrderRequest{ContractID: 2, Size: 2, Price: 100}), "positions on the same underlying should net")
+}
+
+func TestParseContractLabel(t *testing.T) {
+	cases := map[string]ContractLabel{
+		"BTC-Mini-02JUN2021-NextDay":   {Asset: "BTC", Mini: true, Expiry: time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC), Kind: ContractKindNextDay},
+		"ETH-25JUN2021-2500-Call":      {Asset: "ETH", Expiry: time.Date(2021, 6, 25, 0, 0, 0, 0, time.UTC), Strike: 2500, Kind: ContractKindCall},
+		"BTC-Mini-31DEC2021-50000-Put": {Asset: "BTC", Mini: true, Expiry: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), Strike: 50000, Kind: ContractKindPut},
+		"BTC-24SEP2021-Future":         {Asset: "BTC", Expiry: time.Date(2021, 9, 24, 0, 0, 0, 0, time.UTC), Kind: ContractKindFuture},
+	}
+	for label, expected := range cases {
+		parsed, err := ParseContractLabel(label)
+		assert.Nil(t, err, label)
+		assert.Equal(t, expected, parsed, label)
+	}
+
+	for _, label := range []string{"", "BTC", "BTC-32JUN2021-NextDay", "BTC-02JUN2021-2500", "ETH-25JUN2021-abc-Call"} {
+		_, err := ParseContractLabel(label)
+		assert.NotNil(t, err, label)
+	}
+}
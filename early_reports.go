@@ -0,0 +1,65 @@
+package ledgerx
+
+import "time"
+
+// maxEarlyReports bounds the reports held by an earlyReports buffer. Reports
+// for other orders collect while submits are in flight, so a busy feed
+// with overlapping submits would otherwise grow it without limit.
+const maxEarlyReports = 1024
+
+type earlyReport struct {
+	report ActionReportResponse
+	at     time.Time
+}
+
+// earlyReports buffers action reports that arrive on the websocket before
+// the CreateOrder response naming their mid. Reports are only kept while a
+// submit is in flight, the oldest are dropped beyond maxEarlyReports, and
+// the buffer is emptied once no submit is in flight, as nothing can claim
+// what is left. It is not safe for concurrent use; its owner's lock must be
+// held.
+type earlyReports struct {
+	inFlight int
+	reports  []earlyReport
+}
+
+// begin marks a submit as in flight.
+func (b *earlyReports) begin() {
+	b.inFlight++
+}
+
+// add buffers a report for a mid the owner does not know, returning false
+// if no submit is in flight to claim it.
+func (b *earlyReports) add(report ActionReportResponse, at time.Time) bool {
+	if b.inFlight == 0 {
+		return false
+	}
+	if len(b.reports) >= maxEarlyReports {
+		b.reports = b.reports[1:]
+	}
+	b.reports = append(b.reports, earlyReport{report: report, at: at})
+	return true
+}
+
+// end marks a submit as returned and removes the reports buffered for its
+// mid, returning them in arrival order. A failed submit passes an empty
+// mid.
+func (b *earlyReports) end(mid string) []earlyReport {
+	b.inFlight--
+	var claimed []earlyReport
+	if mid != "" {
+		kept := b.reports[:0]
+		for _, e := range b.reports {
+			if e.report.MessageID == mid {
+				claimed = append(claimed, e)
+			} else {
+				kept = append(kept, e)
+			}
+		}
+		b.reports = kept
+	}
+	if b.inFlight == 0 {
+		b.reports = nil
+	}
+	return claimed
+}
@@ -0,0 +1,37 @@
+package ledgerx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarlyReports(t *testing.T) {
+	var b earlyReports
+	at := time.Now()
+	assert.False(t, b.add(ActionReportResponse{MessageID: "a"}, at), "reports should only be held while a submit is in flight")
+
+	b.begin()
+	b.begin()
+	assert.True(t, b.add(ActionReportResponse{MessageID: "a", StatusType: StatusCodeOrderInserted}, at))
+	b.add(ActionReportResponse{MessageID: "b"}, at)
+	b.add(ActionReportResponse{MessageID: "a", StatusType: StatusCodeTradeOccured}, at)
+
+	claimed := b.end("a")
+	if assert.Len(t, claimed, 2) {
+		assert.Equal(t, StatusCodeOrderInserted, claimed[0].report.StatusType)
+		assert.Equal(t, StatusCodeTradeOccured, claimed[1].report.StatusType)
+	}
+	assert.Len(t, b.reports, 1, "other mids should stay buffered for the remaining submit")
+
+	assert.Empty(t, b.end(""))
+	assert.Empty(t, b.reports, "nothing is left to claim the buffer once no submit is in flight")
+
+	b.begin()
+	for i := 0; i < maxEarlyReports+10; i++ {
+		b.add(ActionReportResponse{MessageID: "x", Size: int64(i)}, at)
+	}
+	assert.Len(t, b.reports, maxEarlyReports)
+	assert.Equal(t, int64(10), b.reports[0].report.Size, "the oldest reports should be dropped")
+}
@@ -0,0 +1,163 @@
+package ledgerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tradesServer pages through trades for each derivative type.
+func tradesServer(t *testing.T, trades map[string][]ListTradeData, queries *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/trading/trades", r.URL.Path)
+		*queries = append(*queries, r.URL.RawQuery)
+		q := r.URL.Query()
+		after, _ := time.Parse("2006-01-02T15:04", q.Get("after_ts"))
+		before, _ := time.Parse("2006-01-02T15:04", q.Get("before_ts"))
+		// List newest first, which the exporter must not depend on.
+		var all []ListTradeData
+		for _, t := range trades[q.Get("derivative_type")] {
+			if !t.Timestamp.Before(after) && t.Timestamp.Before(before) {
+				all = append([]ListTradeData{t}, all...)
+			}
+		}
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		end := offset + limit
+		if end > len(all) {
+			end = len(all)
+		}
+		json.NewEncoder(w).Encode(ListTradesResponse{Data: all[offset:end], Metadata: Metadata{TotalCount: int64(len(all))}})
+	}))
+}
+
+func swapTrades(n int, start time.Time) []ListTradeData {
+	trades := make([]ListTradeData, n)
+	for i := range trades {
+		trades[i] = ListTradeData{
+			ID:            int64(1000 + i),
+			ContractID:    22220309,
+			ContractLabel: "BTC-Mini-02JUN2021-NextDay",
+			FilledPrice:   3512300,
+			FilledSize:    int64(i%3 + 1),
+			Fee:           15,
+			OrderID:       fmt.Sprintf("order-%d", i),
+			Side:          "bid",
+			Timestamp:     TradeTimeOf(start.Add(time.Duration(i) * time.Minute)),
+		}
+	}
+	return trades
+}
+
+func TestTradeExporterCSV(t *testing.T) {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var queries []string
+	s := tradesServer(t, map[string][]ListTradeData{
+		"day_ahead_swap": swapTrades(DefaultPageSize+5, from),
+		"options_contract": {{
+			ID: 999, ContractID: 7, ContractLabel: "ETH-25JUN2021-2500-Call", FilledPrice: 12550, FilledSize: 2, Fee: 20,
+			Side: "ask", Timestamp: TradeTimeOf(time.Date(2021, 6, 1, 10, 30, 0, 250e6, time.UTC)),
+		}},
+	}, &queries)
+	defer s.Close()
+
+	exporter := NewTradeExporter(NewLedgerX("", s.URL, s.URL, ""))
+	var buf bytes.Buffer
+	result, err := exporter.Export(&buf, ExportOptions{Format: ExportCSV, From: from, To: from.AddDate(0, 1, 0)})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultPageSize+6, result.Trades)
+	assert.Equal(t, int64(1000+DefaultPageSize+4), result.LastID)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, DefaultPageSize+7, len(lines))
+	assert.Equal(t, "trade_id,timestamp,contract_id,contract_label,asset,expiry,kind,strike,side,price,size,fee,order_id", lines[0])
+	assert.Equal(t, "999,2021-06-01T10:30:00Z,7,ETH-25JUN2021-2500-Call,ETH,2021-06-25,call,2500,ask,125.50,2,0.20,", lines[1])
+	assert.Equal(t, "1000,2021-06-01T00:00:00Z,22220309,BTC-Mini-02JUN2021-NextDay,BTC,2021-06-02,next_day,,bid,35123.00,1,0.15,order-0", lines[2])
+
+	assert.Contains(t, queries[0], "after_ts=2021-06-01T00:00&before_ts=2021-06-02T00:00", "should fetch a day at a time")
+
+	_, err = exporter.Export(&buf, ExportOptions{Format: ExportCSV, From: from})
+	assert.NotNil(t, err, "a missing end should be rejected")
+	_, err = exporter.Export(&buf, ExportOptions{Format: ExportCSV, From: from, To: from})
+	assert.NotNil(t, err, "an empty range should be rejected")
+}
+
+func TestTradeExporterResumesNDJSON(t *testing.T) {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var queries []string
+	s := tradesServer(t, map[string][]ListTradeData{"day_ahead_swap": swapTrades(10, from)}, &queries)
+	defer s.Close()
+
+	exporter := NewTradeExporter(NewLedgerX("", s.URL, s.URL, ""))
+	exporter.DerivativeTypes = []string{"day_ahead_swap"}
+
+	var buf bytes.Buffer
+	result, err := exporter.Export(&buf, ExportOptions{Format: ExportNDJSON, From: from, To: from.Add(5 * time.Minute)})
+	assert.Nil(t, err)
+	assert.Equal(t, ExportResult{Trades: 5, LastID: 1004}, result)
+
+	result, err = exporter.Export(&buf, ExportOptions{Format: ExportNDJSON, From: from, To: from.Add(time.Hour), AfterID: result.LastID})
+	assert.Nil(t, err)
+	assert.Equal(t, ExportResult{Trades: 5, LastID: 1009}, result)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 10)
+	var last ExportedTrade
+	assert.Nil(t, json.Unmarshal([]byte(lines[9]), &last))
+	assert.Equal(t, int64(1009), last.TradeID)
+	assert.Equal(t, SideBid, last.Side)
+	assert.Equal(t, 35123.0, last.Price)
+
+	// Nothing new to export.
+	result, err = exporter.Export(&buf, ExportOptions{Format: ExportNDJSON, From: from, To: from.Add(time.Hour), AfterID: result.LastID})
+	assert.Nil(t, err)
+	assert.Equal(t, ExportResult{Trades: 0, LastID: 1009}, result)
+
+	_, err = exporter.Export(&buf, ExportOptions{Format: "xml"})
+	assert.NotNil(t, err)
+}
+
+func TestTradeExporterResumesAfterFailure(t *testing.T) {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	trades := swapTrades(DefaultPageSize+5, from)
+	var queries []string
+	pages := tradesServer(t, map[string][]ListTradeData{"day_ahead_swap": trades}, &queries)
+	defer pages.Close()
+	failing := true
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing && r.URL.Query().Get("after_ts") != "2021-06-01T00:00" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pages.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+
+	exporter := NewTradeExporter(NewLedgerX("", s.URL, s.URL, ""))
+	exporter.DerivativeTypes = []string{"day_ahead_swap"}
+
+	// The range ends part way through a minute.
+	to := trades[len(trades)-1].Timestamp.Add(30 * time.Second)
+	opts := ExportOptions{Format: ExportCSV, From: from, To: to, Window: time.Hour}
+	var buf bytes.Buffer
+	result, err := exporter.Export(&buf, opts)
+	assert.NotNil(t, err)
+	assert.Equal(t, ExportResult{Trades: 60, LastID: 1059}, result, "the first window should be written before the failure")
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 61)
+
+	failing = false
+	opts.AfterID = result.LastID
+	result, err = exporter.Export(&buf, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, ExportResult{Trades: DefaultPageSize + 5 - 60, LastID: trades[len(trades)-1].ID}, result, "trades in the last partial minute should be included")
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), DefaultPageSize+6)
+	assert.Contains(t, queries[len(queries)-1], "before_ts="+to.Truncate(time.Minute).Add(time.Minute).Format("2006-01-02T15:04"))
+}
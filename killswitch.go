@@ -0,0 +1,182 @@
+package ledgerx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrTradingHalted is returned by order entry methods while the client is
+// halted. The returned error wraps it together with the halt reason.
+var ErrTradingHalted = errors.New("ledgerx trading halted")
+
+const cancelAllConcurrency = 8
+
+// CancelAllFilter narrows CancelAll to a contract and/or side. The zero
+// value cancels every open order.
+type CancelAllFilter struct {
+	ContractID int64
+	Side       Side
+}
+
+func (f *CancelAllFilter) isEmpty() bool {
+	return f == nil || (f.ContractID == 0 && f.Side == "")
+}
+
+func (f *CancelAllFilter) matches(order ListOpenOrdersData) bool {
+	if f.isEmpty() {
+		return true
+	}
+	if f.ContractID != 0 && order.ContractID != f.ContractID {
+		return false
+	}
+	if f.Side != "" && f.Side != SideFromIsAsk(order.IsAsk) {
+		return false
+	}
+	return true
+}
+
+// WithCancelOnDisconnect halts trading and cancels all open orders whenever
+// the websocket connection drops. Trading stays halted until Resume.
+func WithCancelOnDisconnect() Option {
+	return func(l *LedgerX) {
+		l.cancelOnDisconnect = true
+	}
+}
+
+// Halt makes subsequent CreateOrder and CancelAndReplaceOrder calls fail
+// with ErrTradingHalted until Resume is called.
+func (l *LedgerX) Halt(reason string) {
+	l.haltMu.Lock()
+	defer l.haltMu.Unlock()
+	l.halted = true
+	l.haltReason = reason
+	l.logger.Warn("ledgerx trading halted", "reason", reason)
+}
+
+func (l *LedgerX) Resume() {
+	l.haltMu.Lock()
+	defer l.haltMu.Unlock()
+	l.halted = false
+	l.haltReason = ""
+}
+
+// Halted reports whether trading is halted and why.
+func (l *LedgerX) Halted() (bool, string) {
+	l.haltMu.RLock()
+	defer l.haltMu.RUnlock()
+	return l.halted, l.haltReason
+}
+
+func (l *LedgerX) checkHalted() error {
+	if halted, reason := l.Halted(); halted {
+		return fmt.Errorf("%w: %s", ErrTradingHalted, reason)
+	}
+	return nil
+}
+
+// CancelAll cancels every open order matching filter. An unfiltered call
+// uses the exchange's bulk cancel endpoint; filtered calls, or a bulk
+// cancel that fails for any reason, cancel orders individually and
+// concurrently.
+func (l *LedgerX) CancelAll(filter *CancelAllFilter) error {
+	if filter.isEmpty() {
+		err := l.bulkCancel()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errBulkCancelUnsupported) {
+			l.logger.Warn("ledgerx bulk cancel failed, cancelling orders individually", "error", err)
+		}
+	}
+
+	openOrders, err := l.ListOpenOrders()
+	if err != nil {
+		return err
+	}
+
+	var matching []ListOpenOrdersData
+	for _, order := range openOrders.Data {
+		if filter.matches(order) {
+			matching = append(matching, order)
+		}
+	}
+
+	var failed []string
+	for i, err := range l.CancelOrders(matching, cancelAllConcurrency) {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", matching[i].Mid, err.Error()))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("ledgerx cancel all: %d orders failed to cancel (%s)", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+var errBulkCancelUnsupported = errors.New("bulk cancel unsupported")
+
+func (l *LedgerX) bulkCancel() error {
+	requestUrl := fmt.Sprintf("%s/api/orders", l.tradingUrl)
+	req, err := l.makeRequest("DELETE", requestUrl, true, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := l.do(req, endpointOrder)
+	if err != nil {
+		return fmt.Errorf("Error during request execution: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return errBulkCancelUnsupported
+	default:
+		return l.parseResponse(resp, nil)
+	}
+}
+
+func (l *LedgerX) logRiskRejection(err error) {
+	var riskErr *RiskError
+	if errors.As(err, &riskErr) {
+		l.logger.Warn("ledgerx risk check rejected order", "rule", riskErr.Rule, "contract_id", riskErr.ContractID, "value", riskErr.Value, "limit", riskErr.Limit)
+	}
+}
+
+// OnDisconnect registers fn to be called whenever the websocket connection
+// drops. It is called from the client's read loop and must not block.
+func (l *LedgerX) OnDisconnect(fn func()) {
+	l.haltMu.Lock()
+	defer l.haltMu.Unlock()
+	l.disconnectHandlers = append(l.disconnectHandlers, fn)
+}
+
+func (l *LedgerX) handleDisconnect() {
+	l.haltMu.RLock()
+	handlers := l.disconnectHandlers
+	l.haltMu.RUnlock()
+	for _, fn := range handlers {
+		fn()
+	}
+
+	if !l.cancelOnDisconnect {
+		return
+	}
+	// An existing halt only stops new orders; resting ones still go.
+	if halted, _ := l.Halted(); !halted {
+		l.Halt("websocket disconnected")
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		if err := l.CancelAll(nil); err != nil {
+			l.logger.Error("ledgerx cancel on disconnect failed", "error", err)
+		}
+	}()
+}
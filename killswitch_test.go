@@ -0,0 +1,127 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderBookServer struct {
+	bulkSupported bool
+	bulkStatus    int // overrides the bulk cancel response when set
+
+	mu        sync.Mutex
+	bulkCalls int
+	cancelled []string
+	orders    []ListOpenOrdersData
+}
+
+func (s *orderBookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == "DELETE" && r.URL.Path == "/api/orders":
+		s.bulkCalls++
+		if s.bulkStatus != 0 {
+			w.WriteHeader(s.bulkStatus)
+			return
+		}
+		if !s.bulkSupported {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/orders/"):
+		s.cancelled = append(s.cancelled, strings.TrimPrefix(r.URL.Path, "/api/orders/"))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "GET" && r.URL.Path == "/api/open-orders":
+		json.NewEncoder(w).Encode(&ListOpenOrdersResponse{Data: s.orders})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCancelAllUsesBulkEndpoint(t *testing.T) {
+	server := &orderBookServer{bulkSupported: true}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", "", s.URL, "")
+	assert.Nil(t, ledgerClient.CancelAll(nil), "should not error on bulk cancel")
+	assert.Equal(t, 1, server.bulkCalls, "should call bulk endpoint")
+	assert.Empty(t, server.cancelled, "should not cancel individually")
+}
+
+func TestCancelAllFallsBackToIndividualCancels(t *testing.T) {
+	server := &orderBookServer{
+		orders: []ListOpenOrdersData{
+			{Mid: "a", ContractID: 1, IsAsk: true},
+			{Mid: "b", ContractID: 1, IsAsk: false},
+			{Mid: "c", ContractID: 2, IsAsk: true},
+		},
+	}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", "", s.URL, "")
+	assert.Nil(t, ledgerClient.CancelAll(nil), "should not error on fallback cancel")
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, server.cancelled)
+
+	server.cancelled = nil
+	assert.Nil(t, ledgerClient.CancelAll(&CancelAllFilter{ContractID: 1, Side: SideAsk}))
+	assert.Equal(t, []string{"a"}, server.cancelled, "should only cancel matching orders")
+	assert.Equal(t, 1, server.bulkCalls, "filtered cancels should not use the bulk endpoint")
+}
+
+func TestCancelAllFallsBackWhenBulkCancelFails(t *testing.T) {
+	server := &orderBookServer{
+		bulkStatus: http.StatusServiceUnavailable,
+		orders:     []ListOpenOrdersData{{Mid: "a", ContractID: 1}, {Mid: "b", ContractID: 2}},
+	}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", "", s.URL, "")
+	assert.Nil(t, ledgerClient.CancelAll(nil))
+	assert.ElementsMatch(t, []string{"a", "b"}, server.cancelled, "a failed bulk cancel should still cancel every order")
+}
+
+func TestCancelOnDisconnectWhileHalted(t *testing.T) {
+	server := &orderBookServer{bulkSupported: true}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", "", s.URL, "", WithCancelOnDisconnect())
+	ledgerClient.Halt("manual")
+	ledgerClient.handleDisconnect()
+	ledgerClient.wg.Wait()
+
+	server.mu.Lock()
+	assert.Equal(t, 1, server.bulkCalls, "resting orders should be cancelled even when already halted")
+	server.mu.Unlock()
+	_, reason := ledgerClient.Halted()
+	assert.Equal(t, "manual", reason, "the existing halt should be kept")
+}
+
+func TestHaltBlocksOrderEntry(t *testing.T) {
+	client := &failingClient{}
+	ledgerClient := NewLedgerX("", "", "", "")
+	ledgerClient.client = client
+
+	ledgerClient.Halt("manual")
+	_, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 1, Price: 100})
+	assert.True(t, errors.Is(err, ErrTradingHalted), "should fail while halted")
+	assert.Equal(t, 0, client.calls, "should not send the order")
+
+	ledgerClient.Resume()
+	_, err = ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 1, Price: 100})
+	assert.False(t, errors.Is(err, ErrTradingHalted), "should send orders after resume")
+	assert.Equal(t, 1, client.calls)
+}
@@ -2,14 +2,13 @@ package ledgerx
 
 import (
 	"encoding/json"
+	"errors"
 
 	"bytes"
 	"fmt"
 
 	"github.com/gorilla/websocket"
-	"github.com/sirupsen/logrus"
 
-	//log "github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -28,6 +27,12 @@ type LedgerX struct {
 	token        string
 	conn         *websocket.Conn
 	client       clientInterface
+	limiter      *rateLimiter
+	retry        *RetryPolicy
+	middleware   []Middleware
+	logger       Logger
+	metrics      *Metrics
+	tracer       *Tracer
 
 	reconnectTimeout time.Duration
 	readTimeout      time.Duration
@@ -37,11 +42,44 @@ type LedgerX struct {
 	connect chan struct{}
 	stop    chan struct{}
 
+	handlersMu        sync.RWMutex
+	handlers          []MessageHandler
+	dropOnFullChannel bool
+	risk              *RiskManager
+
+	haltMu             sync.RWMutex
+	halted             bool
+	haltReason         string
+	cancelOnDisconnect bool
+	disconnectHandlers []func()
+
+	submits submitLog
+
 	wg sync.WaitGroup
 }
 
-func NewLedgerX(websocketUrl string, restUrl string, tradingUrl string, apiKey string) *LedgerX {
-	return &LedgerX{
+// Option configures optional client behaviour in NewLedgerX.
+type Option func(*LedgerX)
+
+// WithMessageHandler registers a handler that observes every websocket
+// message before it is delivered on the Listen channel.
+func WithMessageHandler(h MessageHandler) Option {
+	return func(l *LedgerX) {
+		l.handlers = append(l.handlers, h)
+	}
+}
+
+// WithRiskManager enforces the manager's limits on CreateOrder and
+// CancelAndReplaceOrder and keeps it fed with websocket updates.
+func WithRiskManager(r *RiskManager) Option {
+	return func(l *LedgerX) {
+		l.risk = r
+		l.handlers = append(l.handlers, r)
+	}
+}
+
+func NewLedgerX(websocketUrl string, restUrl string, tradingUrl string, apiKey string, opts ...Option) *LedgerX {
+	l := &LedgerX{
 		websocketUrl:     websocketUrl,
 		restUrl:          restUrl,
 		tradingUrl:       tradingUrl,
@@ -53,7 +91,12 @@ func NewLedgerX(websocketUrl string, restUrl string, tradingUrl string, apiKey s
 		msg:              make(chan Message, 1024),
 		stop:             make(chan struct{}, 1),
 		client:           http.DefaultClient,
+		logger:           NopLogger{},
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 func (l *LedgerX) ListContracts() (*ListContractsResponse, error) {
@@ -68,9 +111,37 @@ func (l *LedgerX) ListContracts() (*ListContractsResponse, error) {
 		return nil, fmt.Errorf("Error during request creation: %s", err.Error())
 	}
 
-	resp, err := l.client.Do(req)
+	resp, err := l.doRead(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error during request execution: %w", err)
+	}
+	defer resp.Body.Close()
+
+	listContractsResponse := &ListContractsResponse{}
+	parseErr := l.parseResponse(resp, listContractsResponse)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return listContractsResponse, nil
+}
+
+// ListActiveContracts lists a page of active contracts of one derivative
+// type, or of every type when derivativeType is empty. Unlike
+// ListContracts it is not limited to day ahead swaps or to recent listings.
+func (l *LedgerX) ListActiveContracts(derivativeType string, offset int32) (*ListContractsResponse, error) {
+	url := fmt.Sprintf("%s/trading/contracts?active=true&limit=%d&offset=%d", l.restUrl, DefaultPageSize, offset)
+	if derivativeType != "" {
+		url += fmt.Sprintf("&derivative_type=%s", derivativeType)
+	}
+	req, err := l.makeRequest("GET", url, true, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Error during request execution: %s", err.Error())
+		return nil, err
+	}
+
+	resp, err := l.doRead(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error during request execution: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -87,9 +158,9 @@ func (l *LedgerX) ListOpenOrders() (*ListOpenOrdersResponse, error) {
 	url := fmt.Sprintf("%s/api/open-orders", l.tradingUrl)
 	req, err := l.makeRequest("GET", url, true, nil)
 
-	resp, err := l.client.Do(req)
+	resp, err := l.doRead(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error during request creation: %s", err.Error())
+		return nil, fmt.Errorf("Error during request creation: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -102,6 +173,35 @@ func (l *LedgerX) ListOpenOrders() (*ListOpenOrdersResponse, error) {
 	return listOpenOrdersResponse, nil
 }
 
+// GetOrder fetches a single order by mid, including orders that are no
+// longer resting. It returns ErrOrderNotFound if the exchange does not
+// know the mid.
+func (l *LedgerX) GetOrder(mid string) (*GetOrderResponse, error) {
+	url := fmt.Sprintf("%s/api/orders/%s", l.tradingUrl, mid)
+	req, err := l.makeRequest("GET", url, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.doRead(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error during request creation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, mid)
+	}
+
+	getOrderResponse := &GetOrderResponse{}
+	parseErr := l.parseResponse(resp, getOrderResponse)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return getOrderResponse, nil
+}
+
 func (l *LedgerX) ListTrades(derivativeType string, lookbackDays int, asset string, offset int32) (*ListTradesResponse, error) {
 	if derivativeType == "" {
 		derivativeType = "day_ahead_swap"
@@ -116,12 +216,39 @@ func (l *LedgerX) ListTrades(derivativeType string, lookbackDays int, asset stri
 	if asset != "" {
 		url += fmt.Sprintf("&asset=%s", asset)
 	}
+	return l.listTrades(url)
+}
+
+// ListTradesBetween lists trades filled from after until before. The API
+// filters to the minute, so the range is widened to whole minutes and may
+// include trades either side of it. Like ListTrades it defaults to day
+// ahead swaps.
+func (l *LedgerX) ListTradesBetween(derivativeType string, after time.Time, before time.Time, asset string, offset int32) (*ListTradesResponse, error) {
+	if derivativeType == "" {
+		derivativeType = "day_ahead_swap"
+	}
+
+	const layout = "2006-01-02T15:04"
+	after = after.UTC().Truncate(time.Minute)
+	if rounded := before.UTC().Truncate(time.Minute); rounded.Before(before) {
+		before = rounded.Add(time.Minute)
+	}
+	url := fmt.Sprintf("%s/trading/trades?derivative_type=%s&after_ts=%s&before_ts=%s&limit=%d&offset=%d", l.restUrl, derivativeType, after.Format(layout), before.UTC().Format(layout), DefaultPageSize, offset)
+	if asset != "" {
+		url += fmt.Sprintf("&asset=%s", asset)
+	}
+	return l.listTrades(url)
+}
 
+func (l *LedgerX) listTrades(url string) (*ListTradesResponse, error) {
 	req, err := l.makeRequest("GET", url, true, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := l.client.Do(req)
+	resp, err := l.doRead(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error during request creation: %s", err.Error())
+		return nil, fmt.Errorf("Error during request creation: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -132,63 +259,148 @@ func (l *LedgerX) ListTrades(derivativeType string, lookbackDays int, asset stri
 	}
 
 	return listTradesResponse, nil
-
 }
 
+// ListPositions requests a page of positions but, as it always has, returns
+// an empty ListTradesResponse.
+//
+// Deprecated: the positions endpoint does not return trades. Use
+// GetPositions, which parses the response into ListPositionsResponse.
 func (l *LedgerX) ListPositions(offset int32) (*ListTradesResponse, error) {
-	url := fmt.Sprintf("%s/trading/positions?liimt=%v&offset=%v", l.restUrl, DefaultPageSize, offset)
+	if _, err := l.GetPositions(offset); err != nil {
+		return nil, err
+	}
+	return &ListTradesResponse{}, nil
+}
+
+// GetPositions lists a page of positions, including flat ones.
+func (l *LedgerX) GetPositions(offset int32) (*ListPositionsResponse, error) {
+	url := fmt.Sprintf("%s/trading/positions?limit=%v&offset=%v", l.restUrl, DefaultPageSize, offset)
 
 	req, err := l.makeRequest("GET", url, true, nil)
 
-	resp, err := l.client.Do(req)
+	resp, err := l.doRead(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error during request creation: %s", err.Error())
+		return nil, fmt.Errorf("Error during request creation: %w", err)
 	}
 	defer resp.Body.Close()
-	// buf := new(bytes.Buffer)
-	// buf.ReadFrom(resp.Body)
-	// newStr := buf.String()
 
-	// fmt.Println(newStr)
-
-	listTradesResponse := &ListTradesResponse{}
-	// parseErr := l.parseResponse(resp, listTradesResponse)
-	// if parseErr != nil {
-	// 	return nil, parseErr
-	// }
-
-	return listTradesResponse, nil
+	listPositionsResponse := &ListPositionsResponse{}
+	parseErr := l.parseResponse(resp, listPositionsResponse)
+	if parseErr != nil {
+		return nil, parseErr
+	}
 
+	return listPositionsResponse, nil
 }
 
 func (l *LedgerX) CreateOrder(request *CreateOrderRequest) (*CreateOrderResponse, error) {
-	requestUrl := fmt.Sprintf("%s/api/orders", l.tradingUrl)
+	return l.tracer.traceCreateOrder(request, func() (*CreateOrderResponse, error) {
+		return l.createOrder(request)
+	})
+}
+
+func (l *LedgerX) createOrder(request *CreateOrderRequest) (*CreateOrderResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if request.OrderType == "" {
+		withType := *request
+		withType.OrderType = OrderTypeLimit
+		request = &withType
+	}
+	if err := l.checkHalted(); err != nil {
+		return nil, err
+	}
+	if l.risk == nil {
+		return l.submitNewOrder(request)
+	}
+
+	// Reserve the order's exposure so concurrent submits see each other.
+	reservation, err := l.risk.reserveOrder(request)
+	if err != nil {
+		l.logRiskRejection(err)
+		return nil, err
+	}
+	resp, err := l.submitNewOrder(request)
+	mid := ""
+	if err == nil {
+		mid = resp.Data.Mid
+	}
+	l.risk.settleReservation(reservation, mid)
+	return resp, err
+}
 
+func (l *LedgerX) submitNewOrder(request *CreateOrderRequest) (*CreateOrderResponse, error) {
 	requestBody, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling json, %s", err.Error())
 	}
 
-	//log.Println(requestBody)
-	req, err := l.makeRequest("POST", requestUrl, true, bytes.NewReader(requestBody))
-
-	resp, err := l.client.Do(req)
+	l.submits.begin(request)
+	defer l.submits.end(request)
+
+	submitted := time.Now()
+	resp, err := l.submitOrder(requestBody)
+	for attempt := 1; err != nil && l.retry.retryOrder(err, attempt); attempt++ {
+		time.Sleep(l.retry.backoff(attempt))
+		mid, found, lookupErr := l.findSubmittedOrder(request, submitted)
+		if lookupErr != nil {
+			// The outcome is still unknown, resubmitting could double the order.
+			if !errors.Is(lookupErr, ErrOrderOutcomeUnknown) {
+				lookupErr = fmt.Errorf("%w: %s", ErrOrderOutcomeUnknown, lookupErr.Error())
+			}
+			l.metrics.observeOrderEvent("submit_failed")
+			return nil, fmt.Errorf("%w (submit failed: %s)", lookupErr, err.Error())
+		}
+		if found {
+			l.submits.claim(mid)
+			return &CreateOrderResponse{Data: CreateOrderData{Mid: mid}}, nil
+		}
+		resp, err = l.submitOrder(requestBody)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("Error during request creation: %s", err.Error())
+		l.metrics.observeOrderEvent("submit_failed")
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	createOrderResponse := &CreateOrderResponse{}
 	parseErr := l.parseResponse(resp, createOrderResponse)
 	if parseErr != nil {
+		l.metrics.observeOrderEvent("submit_failed")
 		return nil, parseErr
 	}
+	l.submits.claim(createOrderResponse.Data.Mid)
+	l.metrics.observeOrderEvent("submitted")
 
 	return createOrderResponse, nil
 
 }
 
+func (l *LedgerX) submitOrder(requestBody []byte) (*http.Response, error) {
+	requestUrl := fmt.Sprintf("%s/api/orders", l.tradingUrl)
+	req, err := l.makeRequest("POST", requestUrl, true, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.do(req, endpointOrder)
+	if err != nil {
+		return nil, fmt.Errorf("Error during request creation: %w", err)
+	}
+	return resp, nil
+}
+
+// CancelOrder cancels a resting order. It returns ErrOrderNotFound if the
+// order has already filled or been cancelled.
 func (l *LedgerX) CancelOrder(mid string, contractID int32) error {
+	return l.tracer.traceOrderCall("ledgerx.CancelOrder", mid, func() error {
+		return l.cancelOrder(mid, contractID)
+	})
+}
+
+func (l *LedgerX) cancelOrder(mid string, contractID int32) error {
 	requestUrl := fmt.Sprintf("%s/api/orders/%s", l.tradingUrl, mid)
 
 	requestBody, err := json.Marshal(&CancelOrderRequest{
@@ -200,19 +412,41 @@ func (l *LedgerX) CancelOrder(mid string, contractID int32) error {
 
 	req, err := l.makeRequest("DELETE", requestUrl, true, bytes.NewReader(requestBody))
 
-	resp, err := l.client.Do(req)
+	resp, err := l.do(req, endpointOrder)
 	if err != nil {
-		return fmt.Errorf("Error during request creation: %s", err.Error())
+		return fmt.Errorf("Error during request creation: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != 200 && orderNotFound(resp) {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, mid)
+	}
 	if resp.StatusCode != 200 {
 		return l.parseResponse(resp, nil)
 	}
+	l.metrics.observeOrderEvent("cancel_requested")
 	return nil
 }
 
+// CancelAndReplaceOrder amends a resting order. It returns ErrOrderNotFound
+// if the order has already filled or been cancelled.
 func (l *LedgerX) CancelAndReplaceOrder(mid string, request *CancelAndReplaceRequest) error {
+	return l.tracer.traceOrderCall("ledgerx.CancelAndReplaceOrder", mid, func() error {
+		return l.cancelAndReplaceOrder(mid, request)
+	})
+}
+
+func (l *LedgerX) cancelAndReplaceOrder(mid string, request *CancelAndReplaceRequest) error {
+	if err := l.checkHalted(); err != nil {
+		return err
+	}
+	if l.risk != nil {
+		if err := l.risk.CheckReplace(mid, request); err != nil {
+			l.logRiskRejection(err)
+			return err
+		}
+	}
+
 	requestUrl := fmt.Sprintf("%s/api/orders/%s/edit", l.tradingUrl, mid)
 
 	requestBody, err := json.Marshal(request)
@@ -221,23 +455,23 @@ func (l *LedgerX) CancelAndReplaceOrder(mid string, request *CancelAndReplaceReq
 	}
 	req, err := l.makeRequest("POST", requestUrl, true, bytes.NewReader(requestBody))
 
-	resp, err := l.client.Do(req)
+	resp, err := l.do(req, endpointOrder)
 	if err != nil {
-		return fmt.Errorf("Error during request creation: %s", err.Error())
+		return fmt.Errorf("Error during request creation: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != 200 && orderNotFound(resp) {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, mid)
+	}
 	if resp.StatusCode != 200 {
 		return l.parseResponse(resp, nil)
 	}
+	l.metrics.observeOrderEvent("replace_requested")
 	return nil
 }
 
 func (l *LedgerX) makeRequest(method string, requestUrl string, requiresAuth bool, data io.Reader) (*http.Request, error) {
-	//log.Println(strings.NewReader(data.Encode()))
-	// log.Println(requestUrl)
-	// log.Println(l.token)
-
 	req, err := http.NewRequest(method, requestUrl, data)
 	if err != nil {
 		return nil, fmt.Errorf("Error during request creation: %s", err.Error())
@@ -268,8 +502,10 @@ func (l *LedgerX) parseResponse(response *http.Response, resType interface{}) er
 			message = tradeErrorResponse.Error.Message
 		}
 
-		body, _ := ioutil.ReadAll(response.Body)
-		logrus.Errorf("LedgerX Error: %s", string(body))
+		l.logger.Warn("ledgerx api error", "status", response.StatusCode, "message", message)
+		if body, err := ioutil.ReadAll(response.Body); err == nil {
+			l.logger.Debug("ledgerx api error body", "status", response.StatusCode, "body", string(body))
+		}
 
 		switch message {
 		case "INVALID_TOKEN":
@@ -284,6 +520,16 @@ func (l *LedgerX) parseResponse(response *http.Response, resType interface{}) er
 	return parseJson(response, resType)
 }
 
+// orderNotFound reports whether an error response says the exchange has
+// no resting order with the requested mid.
+func orderNotFound(response *http.Response) bool {
+	if response.StatusCode == http.StatusNotFound {
+		return true
+	}
+	var tradeErr TradeErrorResponse
+	return parseJson(response, &tradeErr) == nil && tradeErr.Error.Code == StatusCodeOrderNotFound
+}
+
 func parseJson(response *http.Response, resType interface{}) error {
 	if response.Body == nil {
 		return fmt.Errorf("Error during response parsing: can not read response body")
@@ -296,7 +542,6 @@ func parseJson(response *http.Response, resType interface{}) error {
 
 	response.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-	//log.Println(string(body))
 	if err = json.Unmarshal(body, &resType); err != nil {
 		return fmt.Errorf("Error during response parsing: json marshalling (%s)", err.Error())
 	}
@@ -0,0 +1,452 @@
+// Package ledgerxtest provides a local mock of the LedgerX REST and
+// websocket APIs for testing code built on the ledgerx client.
+package ledgerxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/payaaam/go-ledgerx"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// MockExchange serves the subset of the LedgerX API used by the client.
+// Orders rest until cancelled or filled through Fill; there is no matching
+// engine. Every order event is published to connected websockets as an
+// action report. Cancelled and filled orders can still be fetched by mid.
+type MockExchange struct {
+	server            *httptest.Server
+	HeartbeatInterval time.Duration
+
+	mu        sync.Mutex
+	nextMid   int
+	orders    map[string]*ledgerx.ListOpenOrdersData
+	done      map[string]*ledgerx.ListOpenOrdersData
+	contracts []ledgerx.ListContractsData
+	positions []ledgerx.ListPositionsData
+	trades    []ledgerx.ListTradeData
+	requests  []string
+	conns     map[*websocket.Conn]bool
+	writeMu   sync.Mutex
+}
+
+func NewMockExchange() *MockExchange {
+	m := &MockExchange{
+		HeartbeatInterval: time.Second,
+		orders:            make(map[string]*ledgerx.ListOpenOrdersData),
+		done:              make(map[string]*ledgerx.ListOpenOrdersData),
+		conns:             make(map[*websocket.Conn]bool),
+	}
+	m.server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	return m
+}
+
+func (m *MockExchange) Close() {
+	m.DropConnections()
+	m.server.Close()
+}
+
+// URL is the base URL for both the REST and trading APIs.
+func (m *MockExchange) URL() string {
+	return m.server.URL
+}
+
+func (m *MockExchange) WebsocketURL() string {
+	return "ws" + strings.TrimPrefix(m.server.URL, "http") + "/ws"
+}
+
+// Client returns a ledgerx client pointed at the mock.
+func (m *MockExchange) Client(opts ...ledgerx.Option) *ledgerx.LedgerX {
+	return ledgerx.NewLedgerX(m.WebsocketURL(), m.URL(), m.URL(), "test-token", opts...)
+}
+
+func (m *MockExchange) SetContracts(contracts []ledgerx.ListContractsData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contracts = contracts
+}
+
+func (m *MockExchange) SetPositions(positions []ledgerx.ListPositionsData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.positions = positions
+}
+
+func (m *MockExchange) SetTrades(trades []ledgerx.ListTradeData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trades = trades
+}
+
+// OpenOrders returns the resting orders sorted by mid.
+func (m *MockExchange) OpenOrders() []ledgerx.ListOpenOrdersData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.openOrdersLocked()
+}
+
+// Requests returns every REST request received as "METHOD /path".
+func (m *MockExchange) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+// Fill executes size contracts of a resting order at its price and
+// publishes the trade as an action report.
+func (m *MockExchange) Fill(mid string, size int64) error {
+	m.mu.Lock()
+	order, ok := m.orders[mid]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("order %s not found", mid)
+	}
+	remaining := order.InsertedSize - order.FilledSize
+	if size > remaining {
+		size = remaining
+	}
+	order.FilledSize += size
+	order.FilledPrice = order.InsertedPrice
+	order.StatusType = ledgerx.StatusCodeTradeOccured
+	if order.FilledSize == order.InsertedSize {
+		order.StatusReason = ledgerx.ReasonCodeFullFill
+		m.finishLocked(order)
+	}
+	report := actionReport(order, ledgerx.StatusCodeTradeOccured)
+	report.FilledSize = size
+	report.FilledPrice = order.InsertedPrice
+	report.StatusReason = int(order.StatusReason)
+	side := "bid"
+	if order.IsAsk {
+		side = "ask"
+	}
+	m.trades = append(m.trades, ledgerx.ListTradeData{
+		ID:          int64(len(m.trades) + 1),
+		ContractID:  order.ContractID,
+		FilledPrice: order.InsertedPrice,
+		FilledSize:  size,
+		OrderType:   order.OrderType,
+		OrderID:     mid,
+		Side:        side,
+		Timestamp:   ledgerx.TradeTimeOf(time.Now().UTC()),
+	})
+	m.mu.Unlock()
+
+	m.Publish(report)
+	return nil
+}
+
+// Publish sends v as JSON to every connected websocket.
+func (m *MockExchange) Publish(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	m.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(m.conns))
+	for c := range m.conns {
+		conns = append(conns, c)
+	}
+	m.mu.Unlock()
+
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	for _, c := range conns {
+		c.WriteMessage(websocket.TextMessage, data)
+	}
+}
+
+// DropConnections closes every websocket, simulating a disconnect.
+func (m *MockExchange) DropConnections() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for c := range m.conns {
+		c.Close()
+		delete(m.conns, c)
+	}
+}
+
+// Connections returns the number of connected websockets.
+func (m *MockExchange) Connections() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.conns)
+}
+
+func (m *MockExchange) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ws" {
+		m.serveWebsocket(w, r)
+		return
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, r.Method+" "+r.URL.Path)
+	m.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && path == "/trading/contracts":
+		query := r.URL.Query()
+		resp := ledgerx.ListContractsResponse{Data: []ledgerx.ListContractsData{}}
+		m.mu.Lock()
+		for _, c := range m.contracts {
+			if derivativeType := query.Get("derivative_type"); derivativeType != "" && c.DerivativeType != derivativeType {
+				continue
+			}
+			if query.Get("active") == "true" && !c.Active {
+				continue
+			}
+			resp.Data = append(resp.Data, c)
+		}
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+	case r.Method == "GET" && path == "/trading/positions":
+		m.mu.Lock()
+		resp := ledgerx.ListPositionsResponse{Data: m.positions, Metadata: ledgerx.Metadata{TotalCount: int64(len(m.positions))}}
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+	case r.Method == "GET" && path == "/trading/trades":
+		m.mu.Lock()
+		resp := ledgerx.ListTradesResponse{Data: m.trades, Metadata: ledgerx.Metadata{TotalCount: int64(len(m.trades))}}
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+	case r.Method == "GET" && path == "/api/open-orders":
+		m.mu.Lock()
+		resp := ledgerx.ListOpenOrdersResponse{Data: m.openOrdersLocked()}
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+	case r.Method == "GET" && strings.HasPrefix(path, "/api/orders/"):
+		m.getOrder(w, strings.TrimPrefix(path, "/api/orders/"))
+	case r.Method == "POST" && path == "/api/orders":
+		m.createOrder(w, r)
+	case r.Method == "DELETE" && path == "/api/orders":
+		m.cancelAll(w)
+	case r.Method == "DELETE" && strings.HasPrefix(path, "/api/orders/"):
+		m.cancelOrder(w, strings.TrimPrefix(path, "/api/orders/"))
+	case r.Method == "POST" && strings.HasPrefix(path, "/api/orders/") && strings.HasSuffix(path, "/edit"):
+		m.replaceOrder(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/api/orders/"), "/edit"))
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", 0)
+	}
+}
+
+func (m *MockExchange) createOrder(w http.ResponseWriter, r *http.Request) {
+	var request ledgerx.CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), ledgerx.StatusCodeInvalidOrder)
+		return
+	}
+	if err := request.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), ledgerx.StatusCodeInvalidOrder)
+		return
+	}
+
+	now := ledgerx.NanoTimeOf(time.Now())
+	m.mu.Lock()
+	m.nextMid++
+	mid := fmt.Sprintf("%032x", m.nextMid)
+	order := &ledgerx.ListOpenOrdersData{
+		Mid:           mid,
+		ContractID:    int64(request.ContractID),
+		InsertedPrice: int64(request.Price),
+		InsertedSize:  int64(request.Size),
+		OriginalPrice: int64(request.Price),
+		OriginalSize:  int64(request.Size),
+		IsAsk:         request.IsAsk,
+		OrderType:     string(request.OrderType),
+		InsertedTime:  now,
+		UpdatedTime:   now,
+		StatusType:    ledgerx.StatusCodeOrderInserted,
+	}
+	m.orders[mid] = order
+	report := actionReport(order, ledgerx.StatusCodeOrderInserted)
+	m.mu.Unlock()
+
+	m.Publish(report)
+	writeJSON(w, http.StatusOK, ledgerx.CreateOrderResponse{Data: ledgerx.CreateOrderData{Mid: mid}})
+}
+
+func (m *MockExchange) getOrder(w http.ResponseWriter, mid string) {
+	m.mu.Lock()
+	order, ok := m.orders[mid]
+	if !ok {
+		order, ok = m.done[mid]
+	}
+	var resp ledgerx.GetOrderResponse
+	if ok {
+		resp.Data = *order
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "ORDER_NOT_FOUND", ledgerx.StatusCodeOrderNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (m *MockExchange) cancelOrder(w http.ResponseWriter, mid string) {
+	m.mu.Lock()
+	order, ok := m.orders[mid]
+	if !ok {
+		m.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "ORDER_NOT_FOUND", ledgerx.StatusCodeOrderNotFound)
+		return
+	}
+	order.StatusType = ledgerx.StatusCodeOrderCancelled
+	m.finishLocked(order)
+	report := actionReport(order, ledgerx.StatusCodeOrderCancelled)
+	report.Size = 0
+	m.mu.Unlock()
+
+	m.Publish(report)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockExchange) cancelAll(w http.ResponseWriter) {
+	m.mu.Lock()
+	var reports []ledgerx.ActionReportResponse
+	for _, order := range m.orders {
+		order.StatusType = ledgerx.StatusCodeOrderCancelled
+		m.finishLocked(order)
+		report := actionReport(order, ledgerx.StatusCodeOrderCancelled)
+		report.Size = 0
+		reports = append(reports, report)
+	}
+	m.mu.Unlock()
+
+	for _, report := range reports {
+		m.Publish(report)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockExchange) replaceOrder(w http.ResponseWriter, r *http.Request, mid string) {
+	var request ledgerx.CancelAndReplaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), ledgerx.StatusCodeInvalidOrder)
+		return
+	}
+
+	m.mu.Lock()
+	order, ok := m.orders[mid]
+	if !ok {
+		m.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "ORDER_NOT_FOUND", ledgerx.StatusCodeOrderNotFound)
+		return
+	}
+	order.InsertedPrice = int64(request.Price)
+	order.InsertedSize = order.FilledSize + int64(request.Size)
+	order.UpdatedTime = ledgerx.NanoTimeOf(time.Now())
+	report := actionReport(order, ledgerx.StatusCodeOrderCancelledAndReplaced)
+	m.mu.Unlock()
+
+	m.Publish(report)
+	writeJSON(w, http.StatusOK, ledgerx.CreateOrderResponse{Data: ledgerx.CreateOrderData{Mid: mid}})
+}
+
+func (m *MockExchange) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	m.conns[c] = true
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go m.heartbeat(c, done)
+
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			break
+		}
+	}
+	close(done)
+
+	m.mu.Lock()
+	delete(m.conns, c)
+	m.mu.Unlock()
+	c.Close()
+}
+
+func (m *MockExchange) heartbeat(c *websocket.Conn, done chan struct{}) {
+	if m.HeartbeatInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			data, _ := json.Marshal(ledgerx.HeartbeatMessage{
+				Type:       ledgerx.ChanHeartbeat,
+				Timestamp:  ledgerx.NanoTimeOf(time.Now()),
+				IntervalMS: m.HeartbeatInterval.Milliseconds(),
+			})
+			m.writeMu.Lock()
+			err := c.WriteMessage(websocket.TextMessage, data)
+			m.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// finishLocked moves an order that is no longer resting to the history.
+func (m *MockExchange) finishLocked(order *ledgerx.ListOpenOrdersData) {
+	order.UpdatedTime = ledgerx.NanoTimeOf(time.Now())
+	delete(m.orders, order.Mid)
+	m.done[order.Mid] = order
+}
+
+func (m *MockExchange) openOrdersLocked() []ledgerx.ListOpenOrdersData {
+	orders := make([]ledgerx.ListOpenOrdersData, 0, len(m.orders))
+	for _, order := range m.orders {
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Mid < orders[j].Mid })
+	return orders
+}
+
+func actionReport(order *ledgerx.ListOpenOrdersData, status int) ledgerx.ActionReportResponse {
+	return ledgerx.ActionReportResponse{
+		Type:          ledgerx.ChanActionReport,
+		ContractID:    order.ContractID,
+		MessageID:     order.Mid,
+		Price:         order.InsertedPrice,
+		Size:          order.InsertedSize - order.FilledSize,
+		InsertedPrice: order.InsertedPrice,
+		InsertedSize:  order.InsertedSize,
+		OriginalPrice: order.OriginalPrice,
+		OriginalSize:  order.OriginalSize,
+		IsAsk:         order.IsAsk,
+		OrderType:     order.OrderType,
+		InsertedTime:  order.InsertedTime,
+		UpdatedTime:   order.UpdatedTime,
+		Timestamp:     ledgerx.NanoTimeOf(time.Now()),
+		StatusType:    status,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string, code int32) {
+	writeJSON(w, status, ledgerx.TradeErrorResponse{Error: ledgerx.TradeErrorObject{Message: message, Code: code}})
+}
@@ -0,0 +1,77 @@
+package ledgerx
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the structured logger used by the client. Fields are passed as
+// alternating keys and values, as with log/slog. A *slog.Logger satisfies
+// it directly; see NewSlogLogger on Go 1.21 and later.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// WithLogger sets the client's logger. The default discards all output.
+func WithLogger(logger Logger) Option {
+	return func(l *LedgerX) {
+		l.logger = logger
+	}
+}
+
+// NopLogger discards everything logged to it.
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (NopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (NopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (NopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// NewLogrusLogger adapts a logrus logger or entry. A nil logger uses the
+// logrus standard logger.
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &logrusLogger{logger: logger}
+}
+
+func (l *logrusLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.withFields(keysAndValues).Debug(msg)
+}
+
+func (l *logrusLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.withFields(keysAndValues).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.withFields(keysAndValues).Warn(msg)
+}
+
+func (l *logrusLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.withFields(keysAndValues).Error(msg)
+}
+
+func (l *logrusLogger) withFields(keysAndValues []interface{}) logrus.FieldLogger {
+	if len(keysAndValues) == 0 {
+		return l.logger
+	}
+	fields := make(logrus.Fields, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields["!BADKEY"] = keysAndValues[i]
+		}
+	}
+	return l.logger.WithFields(fields)
+}
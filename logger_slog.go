@@ -0,0 +1,14 @@
+//go:build go1.21
+// +build go1.21
+
+package ledgerx
+
+import "log/slog"
+
+// NewSlogLogger adapts a *slog.Logger. A nil logger uses slog.Default().
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return logger
+}
@@ -0,0 +1,33 @@
+//go:build go1.21
+// +build go1.21
+
+package ledgerx
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLoggerReceivesClientLogs(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"message": "secret detail", "code": 602}}`))
+	}))
+	defer s.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithLogger(NewSlogLogger(logger)))
+
+	err := ledgerClient.CancelOrder("abc", 1)
+	assert.NotNil(t, err, "should return api error")
+	assert.Contains(t, buf.String(), "ledgerx api error")
+	assert.Contains(t, buf.String(), "status=400")
+	assert.False(t, strings.Contains(buf.String(), `"code": 602`), "should not log the body above debug level")
+}
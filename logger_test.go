@@ -0,0 +1,21 @@
+package ledgerx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogrusLoggerFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	NewLogrusLogger(logger).Warn("order rejected", "contract_id", 22220309, "mid", "abc")
+	assert.Contains(t, buf.String(), `"contract_id":22220309`)
+	assert.Contains(t, buf.String(), `"mid":"abc"`)
+	assert.Contains(t, buf.String(), `"level":"warning"`)
+}
@@ -0,0 +1,291 @@
+package ledgerx
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	latencyBuckets      = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	heartbeatLagBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+)
+
+var orderStatusEvents = map[int]string{
+	StatusCodeOrderInserted:             "inserted",
+	StatusCodeTradeOccured:              "traded",
+	StatusCodeMarketOrderNotFilled:      "not_filled",
+	StatusCodeOrderCancelled:            "cancelled",
+	StatusCodeOrderCancelledAndReplaced: "replaced",
+}
+
+// Metrics collects client health counters and histograms and serves them
+// in the Prometheus text exposition format. All methods are safe on a nil
+// receiver, which disables collection.
+type Metrics struct {
+	mu       sync.Mutex
+	families []*metricFamily
+
+	restRequests  *metricFamily
+	restErrors    *metricFamily
+	restLatency   *metricFamily
+	wsMessages    *metricFamily
+	wsReconnects  *metricFamily
+	heartbeatLag  *metricFamily
+	channelDepth  *metricFamily
+	droppedMsgs   *metricFamily
+	orderEvents   *metricFamily
+	lastHeartbeat time.Time
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+	m.restRequests = m.newFamily("ledgerx_rest_requests_total", "REST requests by endpoint and status code.", "counter", nil, "endpoint", "status")
+	m.restErrors = m.newFamily("ledgerx_rest_errors_total", "REST requests that failed or returned a non-2xx status.", "counter", nil, "endpoint", "status")
+	m.restLatency = m.newFamily("ledgerx_rest_request_duration_seconds", "REST request latency.", "histogram", latencyBuckets, "endpoint")
+	m.wsMessages = m.newFamily("ledgerx_ws_messages_total", "Websocket messages received by type.", "counter", nil, "type")
+	m.wsReconnects = m.newFamily("ledgerx_ws_reconnects_total", "Websocket reconnect attempts.", "counter", nil)
+	m.heartbeatLag = m.newFamily("ledgerx_ws_heartbeat_lag_seconds", "Delay of heartbeats beyond the advertised interval.", "histogram", heartbeatLagBuckets)
+	m.channelDepth = m.newFamily("ledgerx_ws_channel_depth", "Messages waiting on the Listen channel.", "gauge", nil)
+	m.droppedMsgs = m.newFamily("ledgerx_ws_messages_dropped_total", "Websocket messages dropped because the Listen channel was full, with WithDropOnFullChannel.", "counter", nil, "type")
+	m.orderEvents = m.newFamily("ledgerx_order_events_total", "Order lifecycle events from REST calls and action reports.", "counter", nil, "event")
+	return m
+}
+
+// WithMetrics records REST, websocket and order metrics into m.
+func WithMetrics(m *Metrics) Option {
+	return func(l *LedgerX) {
+		l.metrics = m
+	}
+}
+
+func (m *Metrics) observeRequest(req *http.Request, resp *http.Response, err error, d time.Duration) {
+	if m == nil {
+		return
+	}
+	endpoint := endpointLabel(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restRequests.add(1, endpoint, status)
+	m.restLatency.observe(d.Seconds(), endpoint)
+	if err != nil || resp.StatusCode >= 300 {
+		m.restErrors.add(1, endpoint, status)
+	}
+}
+
+func (m *Metrics) observeMessage(messageType string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wsMessages.add(1, messageType)
+}
+
+func (m *Metrics) observeHeartbeat(heartbeat HeartbeatMessage) {
+	if m == nil {
+		return
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.lastHeartbeat.IsZero() {
+		interval := time.Duration(heartbeat.IntervalMS) * time.Millisecond
+		lag := now.Sub(m.lastHeartbeat) - interval
+		if lag < 0 {
+			lag = 0
+		}
+		m.heartbeatLag.observe(lag.Seconds())
+	}
+	m.lastHeartbeat = now
+}
+
+func (m *Metrics) observeReconnect() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wsReconnects.add(1)
+}
+
+func (m *Metrics) observeChannel(depth int, dropped string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channelDepth.set(float64(depth))
+	if dropped != "" {
+		m.droppedMsgs.add(1, dropped)
+	}
+}
+
+func (m *Metrics) observeOrderEvent(event string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orderEvents.add(1, event)
+}
+
+func (m *Metrics) observeActionReport(report ActionReportResponse) {
+	event, ok := orderStatusEvents[report.StatusType]
+	if !ok {
+		event = "rejected"
+	}
+	m.observeOrderEvent(event)
+}
+
+// Handler serves the current metrics in text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
+
+// WriteTo writes the current metrics in text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	if m == nil {
+		return 0, nil
+	}
+	var b strings.Builder
+
+	m.mu.Lock()
+	for _, f := range m.families {
+		f.write(&b)
+	}
+	m.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// endpointLabel names a request by method and path, with identifiers such
+// as order mids replaced so label cardinality stays bounded.
+func endpointLabel(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "0123456789") {
+			segments[i] = "{id}"
+		}
+	}
+	return req.Method + " " + strings.Join(segments, "/")
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	count       uint64
+}
+
+func (m *Metrics) newFamily(name, help, kind string, buckets []float64, labels ...string) *metricFamily {
+	f := &metricFamily{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*metricSeries),
+	}
+	m.families = append(m.families, f)
+	return f
+}
+
+func (f *metricFamily) get(labelValues []string) *metricSeries {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: labelValues, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *metricFamily) add(v float64, labelValues ...string) {
+	f.get(labelValues).value += v
+}
+
+func (f *metricFamily) set(v float64, labelValues ...string) {
+	f.get(labelValues).value = v
+}
+
+func (f *metricFamily) observe(v float64, labelValues ...string) {
+	s := f.get(labelValues)
+	for i, upper := range f.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += v
+}
+
+func (f *metricFamily) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+		for i, upper := range f.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "le", formatFloat(upper)), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, strconv.Quote(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", extraName, strconv.Quote(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,90 @@
+package ledgerx
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	metrics := NewMetrics()
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithMetrics(metrics))
+
+	assert.Nil(t, ledgerClient.CancelOrder("5f2a9c0e1b", 1))
+	_, err := ledgerClient.ListOpenOrders()
+	assert.NotNil(t, err, "should return the server error")
+
+	assert.Nil(t, ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "abc", "status_type": 200}`)))
+	assert.Nil(t, ledgerClient.handleMessage([]byte(`{"type": "heartbeat", "interval_ms": 0}`)))
+	assert.Nil(t, ledgerClient.handleMessage([]byte(`{"type": "heartbeat", "interval_ms": 0}`)))
+
+	metricsServer := httptest.NewServer(metrics.Handler())
+	defer metricsServer.Close()
+	resp, err := http.Get(metricsServer.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	text := string(body)
+
+	assert.Contains(t, text, "# TYPE ledgerx_rest_requests_total counter")
+	assert.Contains(t, text, `ledgerx_rest_requests_total{endpoint="DELETE /api/orders/{id}",status="200"} 1`)
+	assert.Contains(t, text, `ledgerx_rest_errors_total{endpoint="GET /api/open-orders",status="500"} 1`)
+	assert.Contains(t, text, `ledgerx_rest_request_duration_seconds_count{endpoint="GET /api/open-orders"} 1`)
+	assert.Contains(t, text, `ledgerx_ws_messages_total{type="heartbeat"} 2`)
+	assert.Contains(t, text, `ledgerx_ws_heartbeat_lag_seconds_bucket{le="+Inf"} 1`)
+	assert.Contains(t, text, `ledgerx_ws_channel_depth 3`)
+	assert.Contains(t, text, `ledgerx_order_events_total{event="inserted"} 1`)
+	assert.Contains(t, text, `ledgerx_order_events_total{event="cancel_requested"} 1`)
+}
+
+func TestPublishBlocksWhenChannelFull(t *testing.T) {
+	ledgerClient := NewLedgerX("", "", "", "", WithMetrics(NewMetrics()))
+	ledgerClient.msg = make(chan Message, 1)
+	ledgerClient.publish(Message{Type: ChanActionReport})
+
+	done := make(chan struct{})
+	go func() {
+		ledgerClient.publish(Message{Type: ChanActionReport})
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("publish should wait for the consumer by default")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	<-ledgerClient.msg
+	<-done
+	assert.Len(t, ledgerClient.msg, 1, "no message should be dropped")
+}
+
+func TestPublishDropsWhenChannelFull(t *testing.T) {
+	metrics := NewMetrics()
+	ledgerClient := NewLedgerX("", "", "", "", WithMetrics(metrics), WithDropOnFullChannel())
+	ledgerClient.msg = make(chan Message, 1)
+
+	ledgerClient.publish(Message{Type: ChanBookTop})
+	ledgerClient.publish(Message{Type: ChanBookTop})
+	assert.Len(t, ledgerClient.msg, 1)
+
+	metricsServer := httptest.NewServer(metrics.Handler())
+	defer metricsServer.Close()
+	resp, err := http.Get(metricsServer.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `ledgerx_ws_messages_dropped_total{type="book_top"} 1`)
+}
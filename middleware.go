@@ -0,0 +1,178 @@
+package ledgerx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	redactedValue   = "REDACTED"
+)
+
+// RoundTripFunc executes a single REST request against restUrl or tradingUrl.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps request execution. Middleware sees every attempt,
+// including rate limit and retry attempts.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware appends middleware to the chain. The first middleware
+// given is the outermost.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(l *LedgerX) {
+		l.middleware = append(l.middleware, middleware...)
+	}
+}
+
+func (l *LedgerX) send(req *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(l.client.Do)
+	if l.metrics != nil {
+		next = LatencyMiddleware(l.metrics.observeRequest)(next)
+	}
+	for i := len(l.middleware) - 1; i >= 0; i-- {
+		next = l.middleware[i](next)
+	}
+	return next(req)
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the id assigned by RequestIDMiddleware, if any.
+func RequestID(req *http.Request) string {
+	if id, ok := req.Context().Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return req.Header.Get(RequestIDHeader)
+}
+
+// RequestIDMiddleware tags each request with a random X-Request-ID header,
+// unless one is already set, and stores it on the request context.
+func RequestIDMiddleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			id := req.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+				req.Header.Set(RequestIDHeader, id)
+			}
+			return next(req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+		}
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// LatencyMiddleware reports the duration of every request to observe.
+func LatencyMiddleware(observe func(req *http.Request, resp *http.Response, err error, d time.Duration)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			observe(req, resp, err, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+// LoggingMiddleware logs each request with its status and latency. The
+// authorization header and token query parameters are never logged.
+func LoggingMiddleware(logger Logger) Middleware {
+	return LatencyMiddleware(func(req *http.Request, resp *http.Response, err error, d time.Duration) {
+		if err != nil {
+			logger.Warn("ledgerx request failed", "method", req.Method, "url", RedactURL(req.URL), "duration", d, "request_id", RequestID(req), "error", err)
+			return
+		}
+		logger.Debug("ledgerx request", "method", req.Method, "url", RedactURL(req.URL), "duration", d, "request_id", RequestID(req), "status", resp.StatusCode)
+	})
+}
+
+// CapturedExchange is a request and response pair recorded by
+// CaptureMiddleware. Headers and URL are redacted.
+type CapturedExchange struct {
+	RequestID      string
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	RequestBody    []byte
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	Duration       time.Duration
+	Err            error
+}
+
+// CaptureMiddleware records full request and response bodies. Bodies are
+// buffered so downstream parsing is unaffected.
+func CaptureMiddleware(capture func(exchange *CapturedExchange)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			exchange := &CapturedExchange{
+				RequestID:     RequestID(req),
+				Method:        req.Method,
+				URL:           RedactURL(req.URL),
+				RequestHeader: RedactHeader(req.Header),
+			}
+			if req.Body != nil {
+				body, err := ioutil.ReadAll(req.Body)
+				req.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				exchange.RequestBody = body
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+
+			start := time.Now()
+			resp, err := next(req)
+			exchange.Duration = time.Since(start)
+			exchange.Err = err
+			if resp != nil {
+				exchange.StatusCode = resp.StatusCode
+				exchange.ResponseHeader = resp.Header.Clone()
+				if resp.Body != nil {
+					body, readErr := ioutil.ReadAll(resp.Body)
+					resp.Body.Close()
+					exchange.ResponseBody = body
+					resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+					if readErr != nil && err == nil {
+						err = readErr
+					}
+				}
+			}
+			capture(exchange)
+			return resp, err
+		}
+	}
+}
+
+// RedactHeader returns a copy of h with credentials removed.
+func RedactHeader(h http.Header) http.Header {
+	redacted := h.Clone()
+	if redacted.Get("Authorization") != "" {
+		redacted.Set("Authorization", redactedValue)
+	}
+	return redacted
+}
+
+// RedactURL returns u as a string with any token query parameter removed.
+func RedactURL(u *url.URL) string {
+	query := u.Query()
+	if query.Get("token") == "" {
+		return u.String()
+	}
+	query.Set("token", redactedValue)
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
@@ -0,0 +1,65 @@
+package ledgerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var seenRequestID string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenRequestID = r.Header.Get(RequestIDHeader)
+		w.Write([]byte(`{"data": {"mid": "abc"}}`))
+	}))
+	defer s.Close()
+
+	var order []string
+	var captured *CapturedExchange
+	var latency time.Duration
+	trace := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next(req)
+			}
+		}
+	}
+
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "secret", WithMiddleware(
+		trace("first"),
+		RequestIDMiddleware(),
+		CaptureMiddleware(func(exchange *CapturedExchange) {
+			captured = exchange
+		}),
+		LatencyMiddleware(func(req *http.Request, resp *http.Response, err error, d time.Duration) {
+			latency = d
+		}),
+		trace("last"),
+	))
+
+	resp, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 1, Price: 100})
+	assert.Nil(t, err, "should not error when creating order")
+	assert.Equal(t, "abc", resp.Data.Mid, "response should still parse after capture")
+
+	assert.Equal(t, []string{"first", "last"}, order)
+	assert.NotEmpty(t, seenRequestID, "should send a request id")
+	assert.Equal(t, seenRequestID, captured.RequestID)
+	assert.Equal(t, "REDACTED", captured.RequestHeader.Get("Authorization"), "should redact the token")
+	assert.Contains(t, string(captured.RequestBody), `"contract_id":1`)
+	assert.Equal(t, `{"data": {"mid": "abc"}}`, string(captured.ResponseBody))
+	assert.Equal(t, http.StatusOK, captured.StatusCode)
+	assert.True(t, latency > 0, "should measure latency")
+}
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("wss://api.ledgerx.com/ws?token=secret")
+	assert.Equal(t, "wss://api.ledgerx.com/ws?token=REDACTED", RedactURL(u))
+
+	u, _ = url.Parse("https://api.ledgerx.com/trading/contracts?limit=1")
+	assert.Equal(t, "https://api.ledgerx.com/trading/contracts?limit=1", RedactURL(u))
+}
@@ -0,0 +1,119 @@
+package ledgerx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOrderNotFound is returned when the exchange has no record of an order.
+var ErrOrderNotFound = errors.New("ledgerx order not found")
+
+type OrderState string
+
+const (
+	OrderStateOpen      OrderState = "open"
+	OrderStateFilled    OrderState = "filled"
+	OrderStateCancelled OrderState = "cancelled"
+	OrderStateRejected  OrderState = "rejected"
+)
+
+// OrderStatus is the state of an order looked up by mid.
+type OrderStatus struct {
+	Mid        string
+	State      OrderState
+	Order      *ListOpenOrdersData // nil when the order was only found in trade history
+	FilledSize int64
+	Trades     []ListTradeData // only searched when the order is not resting
+}
+
+// LookupOrder reports the state of an order by mid, including orders that
+// have since filled or been cancelled, so that an order manager can
+// reconcile after a restart. GetOrder is asked first. If it does not know
+// the mid, the open orders are checked and then the trades since since
+// with a matching order ID. An order found only in trade history is
+// reported as filled, even if the rest of it was later cancelled.
+func (l *LedgerX) LookupOrder(mid string, since time.Time) (*OrderStatus, error) {
+	resp, err := l.GetOrder(mid)
+	if err == nil {
+		order := resp.Data
+		return &OrderStatus{Mid: mid, State: orderState(order), Order: &order, FilledSize: order.FilledSize}, nil
+	}
+	if !errors.Is(err, ErrOrderNotFound) {
+		return nil, err
+	}
+
+	openOrders, err := l.ListOpenOrders()
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range openOrders.Data {
+		if order.Mid == mid {
+			order := order
+			return &OrderStatus{Mid: mid, State: orderState(order), Order: &order, FilledSize: order.FilledSize}, nil
+		}
+	}
+
+	trades, err := l.tradesForOrder(mid, since)
+	if err != nil {
+		return nil, err
+	}
+	if len(trades) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, mid)
+	}
+	status := &OrderStatus{Mid: mid, State: OrderStateFilled, Trades: trades}
+	for _, t := range trades {
+		status.FilledSize += t.FilledSize
+	}
+	return status, nil
+}
+
+func (l *LedgerX) tradesForOrder(mid string, since time.Time) ([]ListTradeData, error) {
+	return l.tradesSince(since, func(t ListTradeData) bool { return t.OrderID == mid })
+}
+
+// tradesSince returns the trades of every derivative type since since that
+// keep accepts.
+func (l *LedgerX) tradesSince(since time.Time, keep func(ListTradeData) bool) ([]ListTradeData, error) {
+	// The trades endpoint filters to the minute, so round the end up.
+	until := time.Now().Add(time.Minute)
+	seen := make(map[int64]bool)
+	var trades []ListTradeData
+	for _, derivativeType := range DerivativeTypes {
+		var offset int32
+		for {
+			resp, err := l.ListTradesBetween(derivativeType, since, until, "", offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range resp.Data {
+				if keep(t) && !seen[t.ID] {
+					seen[t.ID] = true
+					trades = append(trades, t)
+				}
+			}
+
+			offset += int32(len(resp.Data))
+			if len(resp.Data) < DefaultPageSize || int64(offset) >= resp.Metadata.TotalCount {
+				break
+			}
+		}
+	}
+	return trades, nil
+}
+
+func orderState(order ListOpenOrdersData) OrderState {
+	switch {
+	case order.StatusType >= StatusCodeContractNotFound:
+		return OrderStateRejected
+	case order.StatusType == StatusCodeOrderCancelled,
+		order.StatusType == StatusCodeMarketOrderNotFilled,
+		order.StatusReason == ReasonCodeCancelledByExchange:
+		return OrderStateCancelled
+	case order.StatusReason == ReasonCodeFullFill,
+		order.InsertedSize > 0 && order.FilledSize >= order.InsertedSize:
+		return OrderStateFilled
+	default:
+		return OrderStateOpen
+	}
+}
@@ -0,0 +1,118 @@
+package ledgerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureServer serves testdata files by path. Unknown paths are 404s.
+func fixtureServer(t *testing.T, files map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"ORDER_NOT_FOUND","code":601}}`))
+			return
+		}
+		body, err := os.ReadFile(file)
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+func TestListOpenOrdersFixture(t *testing.T) {
+	s := fixtureServer(t, map[string]string{"/api/open-orders": "testdata/open_orders.json"})
+	defer s.Close()
+
+	resp, err := NewLedgerX("", s.URL, s.URL, "token").ListOpenOrders()
+	assert.Nil(t, err)
+	assert.Equal(t, []ListOpenOrdersData{{
+		Mid:           "7d2e5b1c8f4a4e6b9c0d1e2f3a4b5c6d",
+		Type:          "action_report",
+		Mpid:          1104,
+		Cid:           23890,
+		Timestamp:     NanoTimeOf(time.Unix(0, 1622541600123456789).UTC()),
+		Ticks:         1622541600123456789,
+		ContractID:    22220309,
+		OriginalPrice: 3512300,
+		OriginalSize:  5,
+		InsertedPrice: 3510000,
+		InsertedSize:  4,
+		FilledPrice:   3510000,
+		FilledSize:    1,
+		Vwap:          3510000,
+		StatusType:    StatusCodeTradeOccured,
+		IsAsk:         true,
+		InsertedTime:  NanoTimeOf(time.Unix(0, 1622541500000000000).UTC()),
+		UpdatedTime:   NanoTimeOf(time.Unix(0, 1622541600123456789).UTC()),
+		OrderType:     "limit",
+		Clock:         48213,
+	}}, resp.Data)
+}
+
+func TestGetOrderFixture(t *testing.T) {
+	s := fixtureServer(t, map[string]string{"/api/orders/0a1b2c3d4e5f40718293a4b5c6d7e8f9": "testdata/order.json"})
+	defer s.Close()
+	client := NewLedgerX("", s.URL, s.URL, "token")
+
+	resp, err := client.GetOrder("0a1b2c3d4e5f40718293a4b5c6d7e8f9")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3490000), resp.Data.OriginalPrice)
+	assert.Equal(t, int64(3), resp.Data.OriginalSize)
+	assert.Equal(t, int32(StatusCodeOrderCancelled), resp.Data.StatusType)
+
+	_, err = client.GetOrder("missing")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestLookupOrder(t *testing.T) {
+	s := fixtureServer(t, map[string]string{
+		"/api/orders/0a1b2c3d4e5f40718293a4b5c6d7e8f9": "testdata/order.json",
+		"/api/open-orders": "testdata/open_orders.json",
+		"/trading/trades":  "testdata/trades.json",
+	})
+	defer s.Close()
+	client := NewLedgerX("", s.URL, s.URL, "token")
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Known to GetOrder: a partially filled order that was then cancelled.
+	status, err := client.LookupOrder("0a1b2c3d4e5f40718293a4b5c6d7e8f9", since)
+	assert.Nil(t, err)
+	assert.Equal(t, OrderStateCancelled, status.State)
+	assert.Equal(t, int64(1), status.FilledSize)
+
+	// Falls back to the open orders.
+	status, err = client.LookupOrder("7d2e5b1c8f4a4e6b9c0d1e2f3a4b5c6d", since)
+	assert.Nil(t, err)
+	assert.Equal(t, OrderStateOpen, status.State)
+	assert.Equal(t, int64(5), status.Order.OriginalSize)
+
+	// Falls back to trade history.
+	status, err = client.LookupOrder("5f6e7d8c9b0a41b2a3c4d5e6f7a8b9c0", since)
+	assert.Nil(t, err)
+	assert.Equal(t, OrderStateFilled, status.State)
+	assert.Nil(t, status.Order)
+	assert.Equal(t, int64(3), status.FilledSize)
+	assert.Len(t, status.Trades, 2)
+
+	_, err = client.LookupOrder("unknown", since)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestOrderState(t *testing.T) {
+	assert.Equal(t, OrderStateOpen, orderState(ListOpenOrdersData{StatusType: StatusCodeOrderInserted, InsertedSize: 2}))
+	assert.Equal(t, OrderStateOpen, orderState(ListOpenOrdersData{StatusType: StatusCodeTradeOccured, InsertedSize: 2, FilledSize: 1}))
+	assert.Equal(t, OrderStateFilled, orderState(ListOpenOrdersData{StatusType: StatusCodeTradeOccured, StatusReason: ReasonCodeFullFill, InsertedSize: 2, FilledSize: 2}))
+	assert.Equal(t, OrderStateCancelled, orderState(ListOpenOrdersData{StatusType: StatusCodeOrderCancelled, InsertedSize: 2}))
+	assert.Equal(t, OrderStateCancelled, orderState(ListOpenOrdersData{StatusType: StatusCodeMarketOrderNotFilled}))
+	assert.Equal(t, OrderStateRejected, orderState(ListOpenOrdersData{StatusType: StatusCodeOrderRejected}))
+}
@@ -0,0 +1,183 @@
+package ledgerx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TrackedOrder is the state of an order submitted through an OrderTracker.
+type TrackedOrder struct {
+	ClientOrderID string
+	Mid           string
+	Request       CreateOrderRequest
+	StatusType    int
+	StatusReason  int
+	Size          int64 // remaining open size
+	FilledSize    int64 // cumulative filled size
+	Done          bool  // filled, cancelled or rejected
+	Err           error // submit error, if the order was never accepted
+	Reports       []ActionReportResponse
+}
+
+// OrderTracker correlates client order IDs with exchange mids. Action
+// reports can arrive on the websocket before the CreateOrder response, so
+// a bounded number of reports for unknown mids are buffered while any
+// submit is in flight and applied once the mid is known. Register the
+// tracker with the client's message handlers; NewOrderTracker does this
+// for you.
+type OrderTracker struct {
+	client *LedgerX
+
+	mu          sync.Mutex
+	byID        map[string]*TrackedOrder
+	byMid       map[string]*TrackedOrder
+	early       earlyReports
+	subscribers []func(TrackedOrder)
+}
+
+func NewOrderTracker(client *LedgerX) *OrderTracker {
+	t := &OrderTracker{
+		client: client,
+		byID:   make(map[string]*TrackedOrder),
+		byMid:  make(map[string]*TrackedOrder),
+	}
+	client.AddMessageHandler(t)
+	return t
+}
+
+// Subscribe registers fn to receive a copy of an order after every update.
+func (t *OrderTracker) Subscribe(fn func(TrackedOrder)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subscribers = append(t.subscribers, fn)
+}
+
+// Submit creates the order under a generated client order ID.
+func (t *OrderTracker) Submit(request *CreateOrderRequest) (TrackedOrder, error) {
+	return t.SubmitWithID(newClientOrderID(), request)
+}
+
+// SubmitWithID creates the order under clientOrderID, which must be unique
+// within the tracker.
+func (t *OrderTracker) SubmitWithID(clientOrderID string, request *CreateOrderRequest) (TrackedOrder, error) {
+	t.mu.Lock()
+	if _, exists := t.byID[clientOrderID]; exists {
+		t.mu.Unlock()
+		return TrackedOrder{}, fmt.Errorf("duplicate client order id %q", clientOrderID)
+	}
+	order := &TrackedOrder{
+		ClientOrderID: clientOrderID,
+		Request:       *request,
+		Size:          int64(request.Size),
+	}
+	t.byID[clientOrderID] = order
+	t.early.begin()
+	t.mu.Unlock()
+
+	resp, err := t.client.CreateOrder(request)
+
+	t.mu.Lock()
+	if err != nil {
+		t.early.end("")
+		order.Err = err
+		order.Done = true
+	} else {
+		order.Mid = resp.Data.Mid
+		t.byMid[order.Mid] = order
+		for _, e := range t.early.end(order.Mid) {
+			order.apply(e.report)
+		}
+	}
+	snapshot := order.copy()
+	subscribers := t.subscribers
+	t.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(snapshot)
+	}
+	return snapshot, err
+}
+
+func (t *OrderTracker) HandleMessage(msg Message) {
+	report, ok := msg.Data.(ActionReportResponse)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	order, ok := t.byMid[report.MessageID]
+	if !ok {
+		t.early.add(report, time.Now())
+		t.mu.Unlock()
+		return
+	}
+	order.apply(report)
+	snapshot := order.copy()
+	subscribers := t.subscribers
+	t.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(snapshot)
+	}
+}
+
+// Order returns the order submitted under clientOrderID.
+func (t *OrderTracker) Order(clientOrderID string) (TrackedOrder, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	order, ok := t.byID[clientOrderID]
+	if !ok {
+		return TrackedOrder{}, false
+	}
+	return order.copy(), true
+}
+
+// OrderByMid returns the order the exchange knows as mid.
+func (t *OrderTracker) OrderByMid(mid string) (TrackedOrder, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	order, ok := t.byMid[mid]
+	if !ok {
+		return TrackedOrder{}, false
+	}
+	return order.copy(), true
+}
+
+// Forget stops tracking an order to bound the tracker's memory. It does not
+// check Done: a live order is dropped too, and later reports for its mid
+// are ignored. Call it once an order is complete, or once the caller no
+// longer needs its updates, as after a successful CancelOrder.
+func (t *OrderTracker) Forget(clientOrderID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if order, ok := t.byID[clientOrderID]; ok {
+		delete(t.byID, clientOrderID)
+		delete(t.byMid, order.Mid)
+	}
+}
+
+func (o *TrackedOrder) apply(report ActionReportResponse) {
+	o.Reports = append(o.Reports, report)
+	o.StatusType = report.StatusType
+	o.StatusReason = report.StatusReason
+	o.Size = report.Size
+	if report.StatusType == StatusCodeTradeOccured {
+		o.FilledSize += report.FilledSize
+	}
+	o.Done = isTerminalReport(report)
+}
+
+func (o *TrackedOrder) copy() TrackedOrder {
+	c := *o
+	c.Reports = append([]ActionReportResponse(nil), o.Reports...)
+	return c
+}
+
+func newClientOrderID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,68 @@
+package ledgerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderTrackerBuffersEarlyActionReports(t *testing.T) {
+	var ledgerClient *LedgerX
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The exchange publishes the insert and first fill before the REST
+		// response is written.
+		ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "abc", "status_type": 200, "size": 3}`))
+		ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "abc", "status_type": 201, "size": 1, "filled_size": 2, "filled_price": 150}`))
+		ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "other", "status_type": 200, "size": 1}`))
+		w.Write([]byte(`{"data": {"mid": "abc"}}`))
+	}))
+	defer s.Close()
+
+	ledgerClient = NewLedgerX("", s.URL, s.URL, "")
+	tracker := NewOrderTracker(ledgerClient)
+
+	var updates []TrackedOrder
+	tracker.Subscribe(func(order TrackedOrder) {
+		updates = append(updates, order)
+	})
+
+	order, err := tracker.SubmitWithID("quote-1", Buy(1, 3).Limit(150))
+	assert.Nil(t, err, "should submit order")
+	assert.Equal(t, "abc", order.Mid)
+	assert.Equal(t, int64(2), order.FilledSize, "should not lose the first fill")
+	assert.Equal(t, int64(1), order.Size)
+	assert.Len(t, order.Reports, 2)
+	assert.Empty(t, tracker.early, "should drop unclaimed reports once no submit is in flight")
+
+	ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "abc", "status_type": 201, "size": 0, "filled_size": 1}`))
+	order, ok := tracker.Order("quote-1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), order.FilledSize)
+	assert.True(t, order.Done, "should complete on full fill")
+
+	byMid, ok := tracker.OrderByMid("abc")
+	assert.True(t, ok)
+	assert.Equal(t, "quote-1", byMid.ClientOrderID)
+	assert.Len(t, updates, 2)
+
+	_, err = tracker.SubmitWithID("quote-1", Buy(1, 3).Limit(150))
+	assert.NotNil(t, err, "should reject duplicate client order ids")
+
+	tracker.Forget("quote-1")
+	_, ok = tracker.OrderByMid("abc")
+	assert.False(t, ok)
+}
+
+func TestOrderTrackerRecordsSubmitErrors(t *testing.T) {
+	ledgerClient := NewLedgerX("", "", "", "")
+	tracker := NewOrderTracker(ledgerClient)
+	ledgerClient.Halt("test")
+
+	order, err := tracker.Submit(Buy(1, 1).Limit(100))
+	assert.NotNil(t, err)
+	assert.NotEmpty(t, order.ClientOrderID, "should assign a client order id")
+	assert.True(t, order.Done)
+	assert.Equal(t, err, order.Err)
+}
@@ -0,0 +1,143 @@
+package ledgerx
+
+import (
+	"fmt"
+	"math"
+)
+
+// OrderType is the order_type of a new order. An empty type is sent as a
+// limit order. The order entry API has no post-only flag or time-in-force:
+// limit orders rest until they are filled, cancelled or the contract
+// expires, and volatile orders may also be cancelled by the exchange.
+type OrderType string
+
+const (
+	OrderTypeLimit  OrderType = "limit"
+	OrderTypeMarket OrderType = "market"
+)
+
+func (t OrderType) Valid() bool {
+	return t == OrderTypeLimit || t == OrderTypeMarket
+}
+
+type Side string
+
+const (
+	SideBid Side = "bid"
+	SideAsk Side = "ask"
+)
+
+// IsAsk converts the side to the is_ask flag used on the wire.
+func (s Side) IsAsk() bool {
+	return s == SideAsk
+}
+
+func SideFromIsAsk(isAsk bool) Side {
+	if isAsk {
+		return SideAsk
+	}
+	return SideBid
+}
+
+// SwapPurpose declares the purpose of swap orders as required by the CFTC.
+type SwapPurpose string
+
+const (
+	SwapPurposeUndisclosed      SwapPurpose = "undisclosed"
+	SwapPurposeBonaFideHedge    SwapPurpose = "bf_hedge"
+	SwapPurposeNonBonaFideHedge SwapPurpose = "non_bf_hedge"
+	defaultSwapPurpose          SwapPurpose = SwapPurposeUndisclosed
+)
+
+func (p SwapPurpose) Valid() bool {
+	switch p {
+	case SwapPurposeUndisclosed, SwapPurposeBonaFideHedge, SwapPurposeNonBonaFideHedge:
+		return true
+	default:
+		return false
+	}
+}
+
+// Buy starts a limit bid for size contracts. Set the price with Limit, or
+// use Market instead:
+//
+//	client.CreateOrder(ledgerx.Buy(contractID, 1).Limit(1500))
+//
+// Contract IDs outside the int32 range of CreateOrderRequest fail
+// Validate.
+func Buy(contractID int64, size int32) *CreateOrderRequest {
+	return newOrder(contractID, size, SideBid)
+}
+
+// Sell starts a limit ask for size contracts, completed like Buy.
+func Sell(contractID int64, size int32) *CreateOrderRequest {
+	return newOrder(contractID, size, SideAsk)
+}
+
+func newOrder(contractID int64, size int32, side Side) *CreateOrderRequest {
+	request := &CreateOrderRequest{
+		OrderType:   OrderTypeLimit,
+		ContractID:  int32(contractID),
+		IsAsk:       side.IsAsk(),
+		SwapPurpose: defaultSwapPurpose,
+		Size:        size,
+	}
+	if contractID > math.MaxInt32 || contractID < math.MinInt32 {
+		request.ContractID = 0
+		request.buildErr = fmt.Errorf("invalid order: contract id %d is out of range", contractID)
+	}
+	return request
+}
+
+// Limit makes the order a limit order at price, in cents.
+func (c *CreateOrderRequest) Limit(price int32) *CreateOrderRequest {
+	c.OrderType = OrderTypeLimit
+	c.Price = price
+	return c
+}
+
+// Market makes the order a market order.
+func (c *CreateOrderRequest) Market() *CreateOrderRequest {
+	c.OrderType = OrderTypeMarket
+	c.Price = 0
+	return c
+}
+
+func (c *CreateOrderRequest) WithSwapPurpose(purpose SwapPurpose) *CreateOrderRequest {
+	c.SwapPurpose = purpose
+	return c
+}
+
+// WithVolatile sets the volatile flag, which allows the order to be
+// cancelled by the exchange during volatile markets.
+func (c *CreateOrderRequest) WithVolatile(volatile bool) *CreateOrderRequest {
+	c.Volatile = volatile
+	return c
+}
+
+func (c *CreateOrderRequest) Side() Side {
+	return SideFromIsAsk(c.IsAsk)
+}
+
+// Validate checks the order locally so malformed requests are not sent.
+func (c *CreateOrderRequest) Validate() error {
+	if c.buildErr != nil {
+		return c.buildErr
+	}
+	if c.OrderType != "" && !c.OrderType.Valid() {
+		return fmt.Errorf("invalid order: unknown order type %q", c.OrderType)
+	}
+	if c.SwapPurpose != "" && !c.SwapPurpose.Valid() {
+		return fmt.Errorf("invalid order: unknown swap purpose %q", c.SwapPurpose)
+	}
+	if c.ContractID <= 0 {
+		return fmt.Errorf("invalid order: missing contract id")
+	}
+	if c.Size <= 0 {
+		return fmt.Errorf("invalid order: size must be positive, got %d", c.Size)
+	}
+	if c.OrderType != OrderTypeMarket && c.Price <= 0 {
+		return fmt.Errorf("invalid order: limit price must be positive, got %d", c.Price)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBuilders(t *testing.T) {
+	bid := Buy(22220309, 3).Limit(1550)
+	assert.Equal(t, &CreateOrderRequest{
+		OrderType:   OrderTypeLimit,
+		ContractID:  22220309,
+		IsAsk:       false,
+		SwapPurpose: SwapPurposeUndisclosed,
+		Size:        3,
+		Price:       1550,
+	}, bid)
+	assert.Equal(t, SideBid, bid.Side())
+	assert.Nil(t, bid.Validate())
+
+	ask := Sell(22220309, 1).Market().WithSwapPurpose(SwapPurposeBonaFideHedge).WithVolatile(true)
+	assert.True(t, ask.IsAsk)
+	assert.Equal(t, SideAsk, ask.Side())
+	assert.Nil(t, ask.Validate())
+
+	body, err := json.Marshal(ask)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"order_type":"market","contract_id":22220309,"is_ask":true,"swap_purpose":"bf_hedge","size":1,"price":0,"volatile":true}`, string(body))
+}
+
+func TestOrderValidation(t *testing.T) {
+	invalid := []*CreateOrderRequest{
+		Buy(1, 1),
+		{OrderType: "limt", ContractID: 1, Size: 1, Price: 100},
+		Buy(1, 1).Limit(100).WithSwapPurpose("hedge"),
+		Buy(0, 1).Limit(100),
+		Buy(1, 0).Limit(100),
+		Sell(1, 1).Limit(0),
+	}
+	for _, request := range invalid {
+		assert.NotNil(t, request.Validate(), "should reject %s", request.String())
+	}
+}
+
+func TestOrderDefaultsToLimit(t *testing.T) {
+	assert.Nil(t, (&CreateOrderRequest{ContractID: 1, Size: 1, Price: 100}).Validate(), "an empty order type is a limit order")
+	assert.NotNil(t, (&CreateOrderRequest{ContractID: 1, Size: 1}).Validate(), "limit orders need a price")
+	assert.Equal(t, OrderTypeLimit, Buy(1, 1).OrderType)
+}
+
+func TestOrderBuilderContractIDRange(t *testing.T) {
+	request := Buy(1<<31, 1).Limit(100)
+	assert.Equal(t, int32(0), request.ContractID, "should not truncate to another contract")
+	assert.NotNil(t, request.Validate())
+	assert.Nil(t, Sell(1<<31-1, 1).Limit(100).Validate())
+}
+
+func TestCreateOrderRejectsInvalidOrders(t *testing.T) {
+	client := &failingClient{}
+	ledgerClient := NewLedgerX("", "", "", "")
+	ledgerClient.client = client
+
+	_, err := ledgerClient.CreateOrder(&CreateOrderRequest{OrderType: "limt", ContractID: 1, Size: 1, Price: 100})
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, client.calls, "should not send invalid orders")
+}
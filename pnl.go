@@ -0,0 +1,575 @@
+package ledgerx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CostMethod selects how closing fills are matched against open lots.
+type CostMethod int
+
+const (
+	CostFIFO CostMethod = iota
+	CostAverage
+)
+
+// Fill is a single execution, normalised from trade history or an action
+// report. Prices and fees are in cents; fees are positive when paid.
+type Fill struct {
+	TradeID    int64 // zero for fills from action reports
+	OrderID    string
+	ContractID int64
+	Side       Side
+	Price      int64
+	Size       int64
+	Fee        int64
+	Time       time.Time
+}
+
+// qty is the signed size of the fill, positive when buying.
+func (f Fill) qty() int64 {
+	if f.Side == SideAsk {
+		return -f.Size
+	}
+	return f.Size
+}
+
+// FillFromTrade converts a trade from ListTrades.
+func FillFromTrade(t ListTradeData) (Fill, error) {
+	side, ok := tradeSide(t)
+	if !ok {
+		return Fill{}, fmt.Errorf("trade %d: unknown side %q", t.ID, t.Side)
+	}
+	ts := t.Timestamp.Time
+	if ts.IsZero() {
+		ts = t.Created.Time
+	}
+	if ts.IsZero() {
+		return Fill{}, fmt.Errorf("trade %d: missing trade time", t.ID)
+	}
+	return Fill{
+		TradeID:    t.ID,
+		OrderID:    t.OrderID,
+		ContractID: t.ContractID,
+		Side:       side,
+		Price:      t.FilledPrice,
+		Size:       t.FilledSize,
+		Fee:        t.Fee,
+		Time:       ts.UTC(),
+	}, nil
+}
+
+func tradeSide(t ListTradeData) (Side, bool) {
+	switch strings.ToLower(t.Side) {
+	case "bid", "buy":
+		return SideBid, true
+	case "ask", "sell":
+		return SideAsk, true
+	default:
+		return "", false
+	}
+}
+
+// FillFromActionReport converts the fill in an action report. Action
+// reports do not carry fees, which PnLEngine adds from the matching trade.
+func FillFromActionReport(r ActionReportResponse) (Fill, bool) {
+	if r.StatusType != StatusCodeTradeOccured || r.FilledSize == 0 {
+		return Fill{}, false
+	}
+	return Fill{
+		OrderID:    r.MessageID,
+		ContractID: r.ContractID,
+		Side:       SideFromIsAsk(r.IsAsk),
+		Price:      r.FilledPrice,
+		Size:       r.FilledSize,
+		Time:       r.Timestamp.Time,
+	}, true
+}
+
+// RealizedPnL is the PnL realised by one closing fill, expiry or exercise.
+// Amounts are in cents.
+type RealizedPnL struct {
+	Time       time.Time
+	ContractID int64
+	Underlying string
+	Size       int64 // contracts closed
+	Gross      int64
+	Fees       int64
+	Reason     string // "fill", "expiry" or "exercise"
+}
+
+func (r RealizedPnL) Net() int64 {
+	return r.Gross - r.Fees
+}
+
+// ContractPnL is the position and PnL of one contract. Unrealized is zero
+// until a mark is known.
+type ContractPnL struct {
+	ContractID int64
+	Underlying string
+	Size       int64   // net position, negative when short
+	AvgPrice   float64 // cents
+	Realized   int64
+	Fees       int64
+	Unrealized int64
+	Mark       int64
+}
+
+// DailyPnL sums realised PnL and fees per calendar day.
+type DailyPnL struct {
+	Date     string // 2006-01-02
+	Realized int64
+	Fees     int64
+	Net      int64
+}
+
+// UnderlyingPnL sums ContractPnL per underlying asset.
+type UnderlyingPnL struct {
+	Underlying string
+	Realized   int64
+	Unrealized int64
+	Fees       int64
+	Net        int64
+}
+
+type pnlLot struct {
+	size  int64 // signed
+	price int64
+}
+
+type pnlContract struct {
+	lots     []pnlLot // FIFO
+	size     int64    // signed
+	cost     int64    // sum of price * size of open contracts, always positive
+	realized int64
+	fees     int64
+}
+
+// PnLEngine accounts realised and unrealised PnL per contract from trade
+// history and live fills. Trade history and action reports may be applied
+// in any order: the nth fill reported for an order and the nth trade of
+// that order (by trade ID) are the same execution and are counted once.
+// Action reports carry no fee, so a live fill's fee is added when its trade
+// is applied; call AddTrades periodically to pick fees up. Matching assumes
+// the trade history of an order is complete from its first trade.
+//
+// Prices are per unit of the underlying and each contract covers
+// 1/Multiplier units, so PnL is price difference * size / Multiplier.
+// Contracts unknown to the ContractLookup use a multiplier of one.
+type PnLEngine struct {
+	method CostMethod
+	lookup ContractLookup
+
+	mu        sync.RWMutex
+	contracts map[int64]*pnlContract
+	marks     map[int64]int64
+	realized  []RealizedPnL
+	fees      []RealizedPnL // fee-only entries, for daily totals
+	seen      map[string]bool
+	orders    map[string]*pnlOrderFills
+}
+
+// pnlOrderFills pairs the live fills of an order with its trades in
+// sequence.
+type pnlOrderFills struct {
+	reports []Fill // fills from action reports, in arrival order
+	trades  int    // trades applied
+}
+
+func NewPnLEngine(method CostMethod, lookup ContractLookup) *PnLEngine {
+	return &PnLEngine{
+		method:    method,
+		lookup:    lookup,
+		contracts: make(map[int64]*pnlContract),
+		marks:     make(map[int64]int64),
+		seen:      make(map[string]bool),
+		orders:    make(map[string]*pnlOrderFills),
+	}
+}
+
+// AddTrades applies trade history in trade ID order, skipping trades that
+// have already been applied.
+func (e *PnLEngine) AddTrades(trades []ListTradeData) error {
+	fills := make([]Fill, 0, len(trades))
+	for _, t := range trades {
+		fill, err := FillFromTrade(t)
+		if err != nil {
+			return err
+		}
+		fills = append(fills, fill)
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].TradeID < fills[j].TradeID })
+	for _, fill := range fills {
+		e.AddFill(fill)
+	}
+	return nil
+}
+
+// AddFill applies a fill, returning false if it was already applied. A
+// trade matching a live fill that was already applied only adds its fee,
+// and any size the action report did not cover, at the trade's price.
+func (e *PnLEngine) AddFill(fill Fill) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := fillKey(fill)
+	if e.seen[key] {
+		return false
+	}
+	e.seen[key] = true
+
+	qty := fill.qty()
+	if fill.OrderID != "" {
+		o, ok := e.orders[fill.OrderID]
+		if !ok {
+			o = &pnlOrderFills{}
+			e.orders[fill.OrderID] = o
+		}
+		if fill.TradeID == 0 {
+			seq := len(o.reports)
+			o.reports = append(o.reports, fill)
+			if seq < o.trades {
+				// Already applied from trade history.
+				return false
+			}
+		} else {
+			seq := o.trades
+			o.trades++
+			if seq < len(o.reports) {
+				// The trade is the exchange's record of the reported fill.
+				qty -= o.reports[seq].qty()
+			}
+		}
+	}
+
+	c := e.contractLocked(fill.ContractID)
+	c.fees += fill.Fee
+	if fill.Fee != 0 {
+		e.fees = append(e.fees, RealizedPnL{Time: fill.Time, ContractID: fill.ContractID, Underlying: e.underlying(fill.ContractID), Fees: fill.Fee, Reason: "fill"})
+	}
+	if qty != 0 {
+		e.applyLocked(fill.ContractID, c, qty, fill.Price, fill.Time, "fill")
+	}
+	return true
+}
+
+func (e *PnLEngine) HandleMessage(msg Message) {
+	switch data := msg.Data.(type) {
+	case ActionReportResponse:
+		if fill, ok := FillFromActionReport(data); ok {
+			e.AddFill(fill)
+		}
+	case TopBookResponse:
+		if data.Bid > 0 && data.Ask > 0 {
+			e.SetMark(data.ContractID, (data.Bid+data.Ask)/2)
+		}
+	}
+}
+
+// SetMark sets the price used for unrealised PnL, in cents.
+func (e *PnLEngine) SetMark(contractID int64, price int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.marks[contractID] = price
+}
+
+// Expire closes the remaining position in contractID at its settlement
+// value given the underlying's settlement price in cents: intrinsic value
+// for options and the underlying price for other contracts.
+func (e *PnLEngine) Expire(contractID int64, underlyingPrice int64, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c := e.contractLocked(contractID)
+	if c.size == 0 {
+		return
+	}
+	e.applyLocked(contractID, c, -c.size, e.settlementValue(contractID, underlyingPrice), at, "expiry")
+}
+
+// Exercise closes size contracts of a long (or, when assigned, short)
+// option position at intrinsic value. The resulting delivery of the
+// underlying is not tracked by the engine.
+func (e *PnLEngine) Exercise(contractID int64, size int64, underlyingPrice int64, at time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c := e.contractLocked(contractID)
+	if size <= 0 || size > abs(c.size) {
+		return fmt.Errorf("cannot exercise %d of position %d in contract %d", size, c.size, contractID)
+	}
+	qty := -size
+	if c.size < 0 {
+		qty = size
+	}
+	e.applyLocked(contractID, c, qty, e.settlementValue(contractID, underlyingPrice), at, "exercise")
+	return nil
+}
+
+// Contract returns the position and PnL of contractID.
+func (e *PnLEngine) Contract(contractID int64) ContractPnL {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.contractPnLLocked(contractID)
+}
+
+// Contracts returns the position and PnL of every contract traded.
+func (e *PnLEngine) Contracts() []ContractPnL {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	result := make([]ContractPnL, 0, len(e.contracts))
+	for contractID := range e.contracts {
+		result = append(result, e.contractPnLLocked(contractID))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ContractID < result[j].ContractID })
+	return result
+}
+
+// Realized returns every realisation in the order it happened.
+func (e *PnLEngine) Realized() []RealizedPnL {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]RealizedPnL(nil), e.realized...)
+}
+
+// ByDay sums realised PnL and fees per calendar day in loc.
+func (e *PnLEngine) ByDay(loc *time.Location) []DailyPnL {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	days := make(map[string]*DailyPnL)
+	add := func(r RealizedPnL) {
+		date := r.Time.In(loc).Format("2006-01-02")
+		d, ok := days[date]
+		if !ok {
+			d = &DailyPnL{Date: date}
+			days[date] = d
+		}
+		d.Realized += r.Gross
+		d.Fees += r.Fees
+		d.Net = d.Realized - d.Fees
+	}
+	for _, r := range e.realized {
+		add(r)
+	}
+	for _, r := range e.fees {
+		add(r)
+	}
+
+	result := make([]DailyPnL, 0, len(days))
+	for _, d := range days {
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
+
+// ByUnderlying sums PnL per underlying asset.
+func (e *PnLEngine) ByUnderlying() []UnderlyingPnL {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	totals := make(map[string]*UnderlyingPnL)
+	for contractID := range e.contracts {
+		c := e.contractPnLLocked(contractID)
+		u, ok := totals[c.Underlying]
+		if !ok {
+			u = &UnderlyingPnL{Underlying: c.Underlying}
+			totals[c.Underlying] = u
+		}
+		u.Realized += c.Realized
+		u.Unrealized += c.Unrealized
+		u.Fees += c.Fees
+		u.Net = u.Realized + u.Unrealized - u.Fees
+	}
+
+	result := make([]UnderlyingPnL, 0, len(totals))
+	for _, u := range totals {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Underlying < result[j].Underlying })
+	return result
+}
+
+// applyLocked trades qty contracts (positive to buy) at price, closing
+// against the open position first.
+func (e *PnLEngine) applyLocked(contractID int64, c *pnlContract, qty int64, price int64, at time.Time, reason string) {
+	closing := int64(0)
+	if c.size != 0 && (c.size > 0) != (qty > 0) {
+		closing = min64(abs(qty), abs(c.size))
+	}
+
+	if closing > 0 {
+		var gross int64
+		if e.method == CostFIFO {
+			gross = c.closeFIFO(closing, price)
+		} else {
+			gross = c.closeAverage(closing, price)
+		}
+		gross = e.scale(contractID, gross)
+		c.realized += gross
+		e.realized = append(e.realized, RealizedPnL{
+			Time:       at,
+			ContractID: contractID,
+			Underlying: e.underlying(contractID),
+			Size:       closing,
+			Gross:      gross,
+			Reason:     reason,
+		})
+	}
+
+	opening := abs(qty) - closing
+	if opening > 0 {
+		signed := opening
+		if qty < 0 {
+			signed = -opening
+		}
+		c.lots = append(c.lots, pnlLot{size: signed, price: price})
+		c.size += signed
+		c.cost += price * opening
+	}
+}
+
+// closeFIFO closes n contracts against the oldest lots and returns the
+// unscaled PnL.
+func (c *pnlContract) closeFIFO(n int64, price int64) int64 {
+	var gross int64
+	for n > 0 {
+		lot := &c.lots[0]
+		closed := min64(n, abs(lot.size))
+		if lot.size > 0 {
+			gross += (price - lot.price) * closed
+			lot.size -= closed
+			c.size -= closed
+		} else {
+			gross += (lot.price - price) * closed
+			lot.size += closed
+			c.size += closed
+		}
+		c.cost -= lot.price * closed
+		n -= closed
+		if lot.size == 0 {
+			c.lots = c.lots[1:]
+		}
+	}
+	return gross
+}
+
+// closeAverage closes n contracts at the average cost of the position and
+// returns the unscaled PnL.
+func (c *pnlContract) closeAverage(n int64, price int64) int64 {
+	removed := divRound(c.cost*n, abs(c.size))
+	var gross int64
+	if c.size > 0 {
+		gross = price*n - removed
+		c.size -= n
+	} else {
+		gross = removed - price*n
+		c.size += n
+	}
+	c.cost -= removed
+	if c.size == 0 {
+		c.cost = 0
+		c.lots = nil
+	} else {
+		// A single lot at the average price keeps the FIFO state coherent.
+		c.lots = []pnlLot{{size: c.size, price: divRound(c.cost, abs(c.size))}}
+	}
+	return gross
+}
+
+func (e *PnLEngine) contractPnLLocked(contractID int64) ContractPnL {
+	c := e.contracts[contractID]
+	result := ContractPnL{
+		ContractID: contractID,
+		Underlying: e.underlying(contractID),
+		Size:       c.size,
+		Realized:   c.realized,
+		Fees:       c.fees,
+	}
+	if c.size != 0 {
+		result.AvgPrice = float64(c.cost) / float64(abs(c.size))
+	}
+	if mark, ok := e.marks[contractID]; ok {
+		result.Mark = mark
+		value := mark * abs(c.size)
+		if c.size > 0 {
+			result.Unrealized = e.scale(contractID, value-c.cost)
+		} else {
+			result.Unrealized = e.scale(contractID, c.cost-value)
+		}
+	}
+	return result
+}
+
+func (e *PnLEngine) contractLocked(contractID int64) *pnlContract {
+	c, ok := e.contracts[contractID]
+	if !ok {
+		c = &pnlContract{}
+		e.contracts[contractID] = c
+	}
+	return c
+}
+
+func (e *PnLEngine) settlementValue(contractID int64, underlyingPrice int64) int64 {
+	c, ok := e.contract(contractID)
+	if !ok || c.StrikePrice == 0 {
+		return underlyingPrice
+	}
+	strike := int64(c.StrikePrice)
+	if c.IsCall {
+		return max64(underlyingPrice-strike, 0)
+	}
+	return max64(strike-underlyingPrice, 0)
+}
+
+func (e *PnLEngine) scale(contractID int64, amount int64) int64 {
+	if c, ok := e.contract(contractID); ok && c.Multiplier > 1 {
+		return divRound(amount, int64(c.Multiplier))
+	}
+	return amount
+}
+
+func (e *PnLEngine) underlying(contractID int64) string {
+	if c, ok := e.contract(contractID); ok && c.UnderlyingAsset != "" {
+		return c.UnderlyingAsset
+	}
+	return fmt.Sprintf("contract:%d", contractID)
+}
+
+func (e *PnLEngine) contract(contractID int64) (ListContractsData, bool) {
+	if e.lookup == nil {
+		return ListContractsData{}, false
+	}
+	return e.lookup.Contract(contractID)
+}
+
+func fillKey(fill Fill) string {
+	if fill.TradeID != 0 {
+		return fmt.Sprintf("trade:%d", fill.TradeID)
+	}
+	return fmt.Sprintf("report:%s:%d:%d", fill.OrderID, fill.Time.UnixNano(), fill.Size)
+}
+
+// divRound divides rounding half away from zero.
+func divRound(a, b int64) int64 {
+	if (a < 0) != (b < 0) {
+		return (a - b/2) / b
+	}
+	return (a + b/2) / b
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,193 @@
+package ledgerx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contractMap map[int64]ListContractsData
+
+func (m contractMap) Contract(id int64) (ListContractsData, bool) {
+	c, ok := m[id]
+	return c, ok
+}
+
+var pnlContracts = contractMap{
+	1: {ID: 1, UnderlyingAsset: "CBTC", DerivativeType: "day_ahead_swap", Multiplier: 100},
+	2: {ID: 2, UnderlyingAsset: "CBTC", DerivativeType: "options_contract", IsCall: true, StrikePrice: 3000000, Multiplier: 100},
+	3: {ID: 3, UnderlyingAsset: "ETH", DerivativeType: "options_contract", IsCall: false, StrikePrice: 200000, Multiplier: 10},
+}
+
+func trade(id int64, contractID int64, side string, price, size, fee int64, ts string) ListTradeData {
+	return ListTradeData{ID: id, ContractID: contractID, Side: side, FilledPrice: price, FilledSize: size, Fee: fee, Timestamp: mustTradeTime(ts)}
+}
+
+func mustTradeTime(s string) TradeTime {
+	t, err := ParseTradeTime(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPnLFIFO(t *testing.T) {
+	e := NewPnLEngine(CostFIFO, pnlContracts)
+	err := e.AddTrades([]ListTradeData{
+		trade(2, 1, "bid", 3100000, 10, 50, "2021-06-01T14:00:00Z"),
+		trade(1, 1, "bid", 3000000, 10, 50, "2021-06-01T13:00:00Z"),
+		trade(3, 1, "ask", 3200000, 15, 75, "2021-06-02T09:00:00Z"),
+	})
+	assert.Nil(t, err)
+
+	// 10 @ 30000 and 5 @ 31000 close at 32000, per 1/100 BTC contract.
+	c := e.Contract(1)
+	assert.Equal(t, int64(5), c.Size)
+	assert.Equal(t, int64(2000*10+1000*5), c.Realized)
+	assert.Equal(t, int64(175), c.Fees)
+	assert.Equal(t, 3100000.0, c.AvgPrice)
+
+	e.SetMark(1, 3300000)
+	assert.Equal(t, int64(200000*5/100), e.Contract(1).Unrealized)
+
+	// Trades already applied are skipped.
+	assert.Nil(t, e.AddTrades([]ListTradeData{trade(3, 1, "ask", 3200000, 15, 75, "2021-06-02T09:00:00Z")}))
+	assert.Equal(t, int64(5), e.Contract(1).Size)
+
+	assert.Equal(t, []DailyPnL{
+		{Date: "2021-06-01", Realized: 0, Fees: 100, Net: -100},
+		{Date: "2021-06-02", Realized: 25000, Fees: 75, Net: 24925},
+	}, e.ByDay(time.UTC))
+}
+
+func TestPnLAverageCost(t *testing.T) {
+	e := NewPnLEngine(CostAverage, pnlContracts)
+	assert.Nil(t, e.AddTrades([]ListTradeData{
+		trade(1, 1, "bid", 3000000, 10, 0, "2021-06-01T13:00:00Z"),
+		trade(2, 1, "bid", 3100000, 10, 0, "2021-06-01T14:00:00Z"),
+		trade(3, 1, "ask", 3200000, 15, 0, "2021-06-02T09:00:00Z"),
+	}))
+
+	c := e.Contract(1)
+	assert.Equal(t, int64(5), c.Size)
+	assert.Equal(t, int64(1500*15), c.Realized)
+	assert.Equal(t, 3050000.0, c.AvgPrice)
+
+	// Flip through flat into a short.
+	assert.True(t, e.AddFill(Fill{TradeID: 4, ContractID: 1, Side: SideAsk, Price: 3000000, Size: 10}))
+	c = e.Contract(1)
+	assert.Equal(t, int64(-5), c.Size)
+	assert.Equal(t, int64(1500*15-500*5), c.Realized)
+	assert.Equal(t, 3000000.0, c.AvgPrice)
+
+	e.SetMark(1, 2900000)
+	assert.Equal(t, int64(1000*5), e.Contract(1).Unrealized)
+}
+
+func TestPnLLiveFills(t *testing.T) {
+	e := NewPnLEngine(CostFIFO, pnlContracts)
+	report := ActionReportResponse{
+		ContractID:  1,
+		MessageID:   "abc",
+		StatusType:  StatusCodeTradeOccured,
+		FilledPrice: 3000000,
+		FilledSize:  2,
+		Timestamp:   NanoTimeOf(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	e.HandleMessage(Message{Data: report})
+	e.HandleMessage(Message{Data: report})
+	e.HandleMessage(Message{Data: ActionReportResponse{ContractID: 1, StatusType: StatusCodeOrderInserted, Size: 5}})
+	assert.Equal(t, int64(2), e.Contract(1).Size)
+
+	e.HandleMessage(Message{Data: TopBookResponse{ContractID: 1, Bid: 3090000, Ask: 3110000}})
+	c := e.Contract(1)
+	assert.Equal(t, int64(3100000), c.Mark)
+	assert.Equal(t, int64(2000), c.Unrealized)
+}
+
+func TestPnLReconcilesLiveFillsWithTrades(t *testing.T) {
+	e := NewPnLEngine(CostFIFO, pnlContracts)
+	at := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	fill := func(mid string, size int64, offset time.Duration) Message {
+		return Message{Data: ActionReportResponse{
+			ContractID:  1,
+			MessageID:   mid,
+			StatusType:  StatusCodeTradeOccured,
+			FilledPrice: 3000000,
+			FilledSize:  size,
+			Timestamp:   NanoTimeOf(at.Add(offset)),
+		}}
+	}
+	orderTrade := func(id int64, mid string, size, fee int64) ListTradeData {
+		tr := trade(id, 1, "bid", 3000000, size, fee, "2021-06-01T12:00:01Z")
+		tr.OrderID = mid
+		return tr
+	}
+
+	e.HandleMessage(fill("abc", 2, 0))
+	e.HandleMessage(fill("abc", 3, time.Second))
+	assert.Equal(t, int64(5), e.Contract(1).Size)
+	assert.Equal(t, int64(0), e.Contract(1).Fees)
+
+	history := []ListTradeData{orderTrade(11, "abc", 3, 6), orderTrade(10, "abc", 2, 4)}
+	assert.Nil(t, e.AddTrades(history))
+	c := e.Contract(1)
+	assert.Equal(t, int64(5), c.Size, "trades should replace the reported fills, not add to them")
+	assert.Equal(t, int64(10), c.Fees, "live fills should pick up their fees from the trades")
+
+	// The trade can also arrive before the report.
+	assert.Nil(t, e.AddTrades([]ListTradeData{orderTrade(12, "def", 1, 2)}))
+	e.HandleMessage(fill("def", 1, 2*time.Second))
+	assert.Nil(t, e.AddTrades(history))
+	c = e.Contract(1)
+	assert.Equal(t, int64(6), c.Size)
+	assert.Equal(t, int64(12), c.Fees)
+
+	// A trade larger than its report applies the difference.
+	e.HandleMessage(fill("ghi", 1, 3*time.Second))
+	assert.Nil(t, e.AddTrades([]ListTradeData{orderTrade(13, "ghi", 2, 0)}))
+	assert.Equal(t, int64(8), e.Contract(1).Size)
+}
+
+func TestPnLExpiryAndExercise(t *testing.T) {
+	e := NewPnLEngine(CostFIFO, pnlContracts)
+	assert.Nil(t, e.AddTrades([]ListTradeData{
+		trade(1, 2, "bid", 150000, 10, 10, "2021-06-01T13:00:00Z"),
+		trade(2, 3, "ask", 5000, 4, 5, "2021-06-01T13:00:00Z"),
+	}))
+	expiry := time.Date(2021, 6, 25, 8, 0, 0, 0, time.UTC)
+
+	// Exercise 4 calls with BTC at 32,000: intrinsic 2,000 against 1,500 paid.
+	assert.Nil(t, e.Exercise(2, 4, 3200000, expiry))
+	assert.NotNil(t, e.Exercise(2, 7, 3200000, expiry))
+	e.Expire(2, 3200000, expiry)
+	assert.Equal(t, ContractPnL{ContractID: 2, Underlying: "CBTC", Realized: 50000 * 10 / 100, Fees: 10}, e.Contract(2))
+
+	// The short ETH puts expire worthless above the strike.
+	e.Expire(3, 210000, expiry)
+	assert.Equal(t, int64(5000*4/10), e.Contract(3).Realized)
+
+	reasons := []string{}
+	for _, r := range e.Realized() {
+		reasons = append(reasons, r.Reason)
+	}
+	assert.Equal(t, []string{"exercise", "expiry", "expiry"}, reasons)
+
+	assert.Equal(t, []UnderlyingPnL{
+		{Underlying: "CBTC", Realized: 5000, Fees: 10, Net: 4990},
+		{Underlying: "ETH", Realized: 2000, Fees: 5, Net: 1995},
+	}, e.ByUnderlying())
+}
+
+func TestFillFromTrade(t *testing.T) {
+	fill, err := FillFromTrade(trade(9, 1, "ask", 100, 2, 1, "2021-06-01 13:00:00+0000"))
+	assert.Nil(t, err)
+	assert.Equal(t, SideAsk, fill.Side)
+	assert.Equal(t, time.Date(2021, 6, 1, 13, 0, 0, 0, time.UTC), fill.Time)
+
+	_, err = FillFromTrade(trade(9, 1, "sideways", 100, 2, 1, "2021-06-01T13:00:00Z"))
+	assert.NotNil(t, err)
+	_, err = FillFromTrade(ListTradeData{ID: 9, ContractID: 1, Side: "bid", FilledPrice: 100, FilledSize: 2})
+	assert.NotNil(t, err)
+}
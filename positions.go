@@ -0,0 +1,317 @@
+package ledgerx
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PositionChange describes an update to a contract position.
+type PositionChange struct {
+	ContractID   int64
+	Underlying   string
+	Previous     int64
+	Size         int64
+	ExerciseSize int64
+	Source       string // "snapshot", "open_positions_update", "fill" or "reconcile"
+}
+
+// PositionDrift is a difference between the incrementally tracked position
+// and the exchange's REST view of it.
+type PositionDrift struct {
+	ContractID int64
+	Tracked    int64
+	Exchange   int64
+}
+
+type bookPosition struct {
+	size         int64
+	exerciseSize int64
+}
+
+// PositionBook tracks net positions from a REST snapshot, websocket
+// open_positions_update messages and fills reported in action reports.
+// Open position updates are authoritative and replace the tracked size;
+// fills adjust it in between. Periodic reconciliation against the REST
+// positions endpoint detects and corrects drift.
+type PositionBook struct {
+	client *LedgerX
+
+	mu          sync.RWMutex
+	positions   map[int64]*bookPosition
+	underlyings map[int64]string
+	lookup      ContractLookup
+	subscribers []func(PositionChange)
+}
+
+func NewPositionBook(client *LedgerX) *PositionBook {
+	return &PositionBook{
+		client:      client,
+		positions:   make(map[int64]*bookPosition),
+		underlyings: make(map[int64]string),
+	}
+}
+
+// SetContracts provides underlying assets for contracts that have not
+// appeared in a REST snapshot.
+func (b *PositionBook) SetContracts(contracts []ListContractsData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range contracts {
+		b.underlyings[c.ID] = c.UnderlyingAsset
+	}
+}
+
+// SetContractLookup resolves underlyings for contracts that have not
+// appeared in a REST snapshot, typically through a ContractRegistry.
+func (b *PositionBook) SetContractLookup(lookup ContractLookup) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lookup = lookup
+}
+
+// Subscribe registers fn to be called after every position change.
+func (b *PositionBook) Subscribe(fn func(PositionChange)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Seed replaces the tracked positions with the REST positions snapshot.
+func (b *PositionBook) Seed() error {
+	snapshot, err := b.fetchSnapshot()
+	if err != nil {
+		return err
+	}
+	b.apply(snapshot, "snapshot")
+	return nil
+}
+
+// Reconcile compares the tracked positions with the REST snapshot,
+// returning any drift, and then adopts the snapshot.
+func (b *PositionBook) Reconcile() ([]PositionDrift, error) {
+	snapshot, err := b.fetchSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	var drifts []PositionDrift
+	for contractID, p := range snapshot {
+		tracked := b.sizeLocked(contractID)
+		if tracked != p.size {
+			drifts = append(drifts, PositionDrift{ContractID: contractID, Tracked: tracked, Exchange: p.size})
+		}
+	}
+	for contractID, p := range b.positions {
+		if _, ok := snapshot[contractID]; !ok && p.size != 0 {
+			drifts = append(drifts, PositionDrift{ContractID: contractID, Tracked: p.size})
+		}
+	}
+	b.mu.RUnlock()
+
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].ContractID < drifts[j].ContractID })
+	b.apply(snapshot, "reconcile")
+	return drifts, nil
+}
+
+// StartReconciliation reconciles every interval until the returned stop
+// function is called. onDrift is called when drift is found and onError
+// when the snapshot cannot be fetched; either may be nil.
+func (b *PositionBook) StartReconciliation(interval time.Duration, onDrift func([]PositionDrift), onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				drifts, err := b.Reconcile()
+				if err != nil {
+					if onError != nil {
+						onError(err)
+					}
+					continue
+				}
+				if len(drifts) > 0 && onDrift != nil {
+					onDrift(drifts)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (b *PositionBook) HandleMessage(msg Message) {
+	switch data := msg.Data.(type) {
+	case OpenPositionsMessage:
+		snapshot := make(map[int64]bookPosition, len(data.Positions))
+		for _, p := range data.Positions {
+			snapshot[p.ContractID] = bookPosition{size: p.Size, exerciseSize: p.ExerciseSize}
+		}
+		b.update(snapshot, "open_positions_update")
+	case ActionReportResponse:
+		if data.StatusType != StatusCodeTradeOccured || data.FilledSize == 0 {
+			return
+		}
+		delta := data.FilledSize
+		if data.IsAsk {
+			delta = -delta
+		}
+
+		b.mu.Lock()
+		p := b.positionLocked(data.ContractID)
+		change := PositionChange{
+			ContractID:   data.ContractID,
+			Underlying:   b.underlyingLocked(data.ContractID),
+			Previous:     p.size,
+			Size:         p.size + delta,
+			ExerciseSize: p.exerciseSize,
+			Source:       "fill",
+		}
+		p.size = change.Size
+		subscribers := b.subscribers
+		b.mu.Unlock()
+
+		notify(subscribers, []PositionChange{change})
+	}
+}
+
+// Position returns the net size and exercise size for a contract.
+func (b *PositionBook) Position(contractID int64) (size int64, exerciseSize int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if p, ok := b.positions[contractID]; ok {
+		return p.size, p.exerciseSize
+	}
+	return 0, 0
+}
+
+// Positions returns the net size of every tracked contract.
+func (b *PositionBook) Positions() map[int64]int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	positions := make(map[int64]int64, len(b.positions))
+	for contractID, p := range b.positions {
+		positions[contractID] = p.size
+	}
+	return positions
+}
+
+// NetByUnderlying returns the net position summed per underlying asset.
+// Contracts with unknown underlyings are keyed by "contract:<id>".
+func (b *PositionBook) NetByUnderlying() map[string]int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	net := make(map[string]int64)
+	for contractID, p := range b.positions {
+		net[b.underlyingLocked(contractID)] += p.size
+	}
+	return net
+}
+
+func (b *PositionBook) fetchSnapshot() (map[int64]bookPosition, error) {
+	snapshot := make(map[int64]bookPosition)
+	var offset int32
+	for {
+		resp, err := b.client.GetPositions(offset)
+		if err != nil {
+			return nil, err
+		}
+
+		b.mu.Lock()
+		for _, p := range resp.Data {
+			snapshot[p.Contract.ID] = bookPosition{size: p.NetSize(), exerciseSize: p.ExercisedSize}
+			if p.Contract.UnderlyingAsset != "" {
+				b.underlyings[p.Contract.ID] = p.Contract.UnderlyingAsset
+			}
+		}
+		b.mu.Unlock()
+
+		offset += int32(len(resp.Data))
+		if len(resp.Data) < DefaultPageSize || int64(offset) >= resp.Metadata.TotalCount {
+			return snapshot, nil
+		}
+	}
+}
+
+// apply replaces all tracked positions with snapshot.
+func (b *PositionBook) apply(snapshot map[int64]bookPosition, source string) {
+	b.mu.Lock()
+	for contractID := range b.positions {
+		if _, ok := snapshot[contractID]; !ok {
+			snapshot[contractID] = bookPosition{}
+		}
+	}
+	b.mu.Unlock()
+	b.update(snapshot, source)
+}
+
+// update sets the positions in snapshot, leaving other contracts as they are.
+func (b *PositionBook) update(snapshot map[int64]bookPosition, source string) {
+	b.mu.Lock()
+	var changes []PositionChange
+	for contractID, next := range snapshot {
+		p := b.positionLocked(contractID)
+		if p.size == next.size && p.exerciseSize == next.exerciseSize {
+			continue
+		}
+		changes = append(changes, PositionChange{
+			ContractID:   contractID,
+			Underlying:   b.underlyingLocked(contractID),
+			Previous:     p.size,
+			Size:         next.size,
+			ExerciseSize: next.exerciseSize,
+			Source:       source,
+		})
+		*p = next
+	}
+	subscribers := b.subscribers
+	b.mu.Unlock()
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ContractID < changes[j].ContractID })
+	notify(subscribers, changes)
+}
+
+func (b *PositionBook) positionLocked(contractID int64) *bookPosition {
+	p, ok := b.positions[contractID]
+	if !ok {
+		p = &bookPosition{}
+		b.positions[contractID] = p
+	}
+	return p
+}
+
+func (b *PositionBook) sizeLocked(contractID int64) int64 {
+	if p, ok := b.positions[contractID]; ok {
+		return p.size
+	}
+	return 0
+}
+
+func (b *PositionBook) underlyingLocked(contractID int64) string {
+	if underlying := b.underlyings[contractID]; underlying != "" {
+		return underlying
+	}
+	if b.lookup != nil {
+		if c, ok := b.lookup.Contract(contractID); ok && c.UnderlyingAsset != "" {
+			return c.UnderlyingAsset
+		}
+	}
+	return fmt.Sprintf("contract:%d", contractID)
+}
+
+func notify(subscribers []func(PositionChange), changes []PositionChange) {
+	for _, change := range changes {
+		for _, fn := range subscribers {
+			fn(change)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func positionsServer(positions *[]ListPositionsData) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&ListPositionsResponse{
+			Data:     *positions,
+			Metadata: Metadata{TotalCount: int64(len(*positions))},
+		})
+	}))
+}
+
+func TestPositionBookTracksUpdates(t *testing.T) {
+	positions := []ListPositionsData{
+		{Type: "long", Size: 5, Contract: ListContractsData{ID: 1, UnderlyingAsset: "CBTC"}},
+		{Type: "short", Size: 3, ExercisedSize: 1, Contract: ListContractsData{ID: 2, UnderlyingAsset: "CBTC"}},
+		{Type: "long", Size: 2, Contract: ListContractsData{ID: 3, UnderlyingAsset: "ETH"}},
+	}
+	s := positionsServer(&positions)
+	defer s.Close()
+
+	book := NewPositionBook(NewLedgerX("", s.URL, s.URL, ""))
+	var changes []PositionChange
+	book.Subscribe(func(change PositionChange) {
+		changes = append(changes, change)
+	})
+
+	assert.Nil(t, book.Seed(), "should seed from REST")
+	assert.Len(t, changes, 3)
+	size, exercise := book.Position(2)
+	assert.Equal(t, int64(-3), size)
+	assert.Equal(t, int64(1), exercise)
+	assert.Equal(t, map[string]int64{"CBTC": 2, "ETH": 2}, book.NetByUnderlying())
+
+	changes = nil
+	book.HandleMessage(Message{Type: ChanActionReport, Data: ActionReportResponse{
+		ContractID: 1, StatusType: StatusCodeTradeOccured, FilledSize: 2, IsAsk: true,
+	}})
+	size, _ = book.Position(1)
+	assert.Equal(t, int64(3), size, "ask fill should reduce the position")
+	assert.Equal(t, []PositionChange{{ContractID: 1, Underlying: "CBTC", Previous: 5, Size: 3, Source: "fill"}}, changes)
+
+	book.HandleMessage(Message{Type: ChanOpenPositionsUpdate, Data: OpenPositionsMessage{
+		Positions: []Position{{ContractID: 3, Size: 4}},
+	}})
+	size, _ = book.Position(3)
+	assert.Equal(t, int64(4), size, "open positions update should replace the size")
+	size, _ = book.Position(1)
+	assert.Equal(t, int64(3), size, "other contracts should be unchanged")
+}
+
+func TestPositionBookReconcileReportsDrift(t *testing.T) {
+	positions := []ListPositionsData{
+		{Type: "long", Size: 5, Contract: ListContractsData{ID: 1}},
+	}
+	s := positionsServer(&positions)
+	defer s.Close()
+
+	book := NewPositionBook(NewLedgerX("", s.URL, s.URL, ""))
+	assert.Nil(t, book.Seed())
+
+	drifts, err := book.Reconcile()
+	assert.Nil(t, err)
+	assert.Empty(t, drifts, "should not drift when views agree")
+
+	book.HandleMessage(Message{Type: ChanActionReport, Data: ActionReportResponse{
+		ContractID: 2, StatusType: StatusCodeTradeOccured, FilledSize: 1,
+	}})
+	positions = []ListPositionsData{
+		{Type: "long", Size: 6, Contract: ListContractsData{ID: 1}},
+	}
+
+	drifts, err = book.Reconcile()
+	assert.Nil(t, err)
+	assert.Equal(t, []PositionDrift{
+		{ContractID: 1, Tracked: 5, Exchange: 6},
+		{ContractID: 2, Tracked: 1, Exchange: 0},
+	}, drifts)
+	assert.Equal(t, map[int64]int64{1: 6, 2: 0}, book.Positions(), "should adopt the exchange view")
+}
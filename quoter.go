@@ -0,0 +1,338 @@
+package ledgerx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Quote is the target price and size for one side of a two-sided quote.
+// A zero Size means no order should rest on that side.
+type Quote struct {
+	Price int32
+	Size  int32
+}
+
+// QuoteFill is a fill against one of the Quoter's resting orders.
+type QuoteFill struct {
+	ContractID int64
+	Side       Side
+	Mid        string
+	Price      int64
+	Size       int64 // contracts filled by this report
+	Remaining  int64 // contracts still resting on the exchange
+}
+
+// QuoteState is the live state of one side of a quote.
+type QuoteState struct {
+	ContractID int64
+	Side       Side
+	Target     Quote
+	Mid        string // empty when no order is resting
+	Price      int32
+	Size       int64 // resting size
+	Filled     int64 // contracts filled since the order was placed
+}
+
+type quoteKey struct {
+	contractID int64
+	side       Side
+}
+
+type quoteLeg struct {
+	QuoteState
+	clientOrderID string
+}
+
+// Quoter keeps a two-sided quote resting per contract. SetQuote places the
+// initial orders with CreateOrder and amends them in place with
+// CancelAndReplaceOrder when the target changes. Fills are tracked from
+// action reports; a fully filled side is not replenished until the next
+// SetQuote. Quotes are pulled when the websocket disconnects and when an
+// order is rejected by the risk manager or a halt.
+type Quoter struct {
+	client  *LedgerX
+	tracker *OrderTracker
+
+	// ops serialises order entry so that a side never has two requests in
+	// flight. mu protects the legs and is never held across a request.
+	ops         sync.Mutex
+	mu          sync.Mutex
+	legs        map[quoteKey]*quoteLeg
+	byClientID  map[string]*quoteLeg
+	subscribers []func(QuoteFill)
+}
+
+func NewQuoter(client *LedgerX) *Quoter {
+	q := &Quoter{
+		client:     client,
+		tracker:    NewOrderTracker(client),
+		legs:       make(map[quoteKey]*quoteLeg),
+		byClientID: make(map[string]*quoteLeg),
+	}
+	q.tracker.Subscribe(q.handleOrder)
+	client.OnDisconnect(func() {
+		go func() {
+			if err := q.PullAll(); err != nil {
+				client.logger.Error("ledgerx quoter pull on disconnect failed", "error", err)
+			}
+		}()
+	})
+	return q
+}
+
+// OnFill registers fn to be called for every fill against a quote. It is
+// called from the client's read loop and must not block.
+func (q *Quoter) OnFill(fn func(QuoteFill)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.subscribers = append(q.subscribers, fn)
+}
+
+// SetQuote moves the quote on contractID to the given bid and ask, placing,
+// amending or cancelling orders as needed. If an order is rejected by a
+// risk check or because trading is halted, both sides of the contract are
+// pulled and the rejection is returned.
+func (q *Quoter) SetQuote(contractID int64, bid Quote, ask Quote) error {
+	q.ops.Lock()
+	defer q.ops.Unlock()
+
+	err := q.setSide(contractID, SideBid, bid)
+	if err == nil {
+		err = q.setSide(contractID, SideAsk, ask)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var riskErr *RiskError
+	if errors.As(err, &riskErr) || errors.Is(err, ErrTradingHalted) {
+		if pullErr := q.pull(contractID); pullErr != nil {
+			q.client.logger.Error("ledgerx quoter pull failed", "contract_id", contractID, "error", pullErr)
+		}
+	}
+	return err
+}
+
+// Pull cancels both sides of the quote on contractID.
+func (q *Quoter) Pull(contractID int64) error {
+	q.ops.Lock()
+	defer q.ops.Unlock()
+	return q.pull(contractID)
+}
+
+// PullAll cancels every resting quote.
+func (q *Quoter) PullAll() error {
+	q.ops.Lock()
+	defer q.ops.Unlock()
+
+	q.mu.Lock()
+	contracts := make(map[int64]bool)
+	for key := range q.legs {
+		contracts[key.contractID] = true
+	}
+	q.mu.Unlock()
+
+	var failed []string
+	for contractID := range contracts {
+		if err := q.pull(contractID); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("ledgerx quoter pull all: %v", failed)
+	}
+	return nil
+}
+
+// Quotes returns the state of both sides of every quoted contract.
+func (q *Quoter) Quotes() []QuoteState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	states := make([]QuoteState, 0, len(q.legs))
+	for _, leg := range q.legs {
+		states = append(states, leg.QuoteState)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].ContractID != states[j].ContractID {
+			return states[i].ContractID < states[j].ContractID
+		}
+		return states[i].Side < states[j].Side
+	})
+	return states
+}
+
+// State returns the state of one side of the quote on contractID.
+func (q *Quoter) State(contractID int64, side Side) (QuoteState, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	leg, ok := q.legs[quoteKey{contractID, side}]
+	if !ok {
+		return QuoteState{}, false
+	}
+	return leg.QuoteState, true
+}
+
+func (q *Quoter) pull(contractID int64) error {
+	var failed []string
+	for _, side := range []Side{SideBid, SideAsk} {
+		if err := q.setSide(contractID, side, Quote{}); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", side, err.Error()))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("ledgerx quoter pull contract %d: %v", contractID, failed)
+	}
+	return nil
+}
+
+// setSide must be called with q.ops held.
+func (q *Quoter) setSide(contractID int64, side Side, target Quote) error {
+	key := quoteKey{contractID, side}
+
+	q.mu.Lock()
+	leg, ok := q.legs[key]
+	if !ok {
+		leg = &quoteLeg{QuoteState: QuoteState{ContractID: contractID, Side: side}}
+		q.legs[key] = leg
+	}
+	leg.Target = target
+	mid, price, size := leg.Mid, leg.Price, leg.Size
+	q.mu.Unlock()
+
+	switch {
+	case target.Size <= 0 && mid == "":
+		return nil
+	case target.Size <= 0:
+		err := q.client.CancelOrder(mid, int32(contractID))
+		if err != nil && !errors.Is(err, ErrOrderNotFound) {
+			return err
+		}
+		q.clearLeg(leg, mid)
+		return nil
+	case mid == "":
+		return q.place(leg, contractID, side, target)
+	case price == target.Price && size == int64(target.Size):
+		return nil
+	default:
+		err := q.client.CancelAndReplaceOrder(mid, &CancelAndReplaceRequest{
+			ContractID: int32(contractID),
+			Price:      target.Price,
+			Size:       target.Size,
+		})
+		if errors.Is(err, ErrOrderNotFound) {
+			// The order filled or was cancelled under us; place afresh.
+			q.clearLeg(leg, mid)
+			return q.place(leg, contractID, side, target)
+		}
+		if err != nil {
+			return err
+		}
+		q.mu.Lock()
+		if leg.Mid == mid {
+			leg.Price = target.Price
+			leg.Size = int64(target.Size)
+		}
+		q.mu.Unlock()
+		return nil
+	}
+}
+
+func (q *Quoter) place(leg *quoteLeg, contractID int64, side Side, target Quote) error {
+	request := Buy(contractID, target.Size).Limit(target.Price)
+	if side == SideAsk {
+		request = Sell(contractID, target.Size).Limit(target.Price)
+	}
+
+	clientOrderID := newClientOrderID()
+	q.mu.Lock()
+	leg.clientOrderID = clientOrderID
+	leg.Price = target.Price
+	leg.Size = int64(target.Size)
+	leg.Filled = 0
+	q.byClientID[clientOrderID] = leg
+	q.mu.Unlock()
+
+	if _, err := q.tracker.SubmitWithID(clientOrderID, request); err != nil {
+		q.mu.Lock()
+		delete(q.byClientID, clientOrderID)
+		leg.clientOrderID = ""
+		leg.Size = 0
+		q.mu.Unlock()
+		q.tracker.Forget(clientOrderID)
+		return err
+	}
+
+	// A terminal report may have been handled since the snapshot was taken,
+	// in which case handleOrder has already released the leg.
+	q.mu.Lock()
+	if current, ok := q.tracker.Order(clientOrderID); ok && !current.Done && leg.clientOrderID == clientOrderID {
+		leg.Mid = current.Mid
+	}
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *Quoter) clearLeg(leg *quoteLeg, mid string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if leg.Mid != mid {
+		return
+	}
+	delete(q.byClientID, leg.clientOrderID)
+	q.tracker.Forget(leg.clientOrderID)
+	leg.Mid = ""
+	leg.clientOrderID = ""
+	leg.Price = 0
+	leg.Size = 0
+}
+
+// handleOrder is called by the tracker after every update to an order.
+func (q *Quoter) handleOrder(order TrackedOrder) {
+	q.mu.Lock()
+	leg, ok := q.byClientID[order.ClientOrderID]
+	if !ok || order.Mid == "" {
+		q.mu.Unlock()
+		return
+	}
+
+	var fill *QuoteFill
+	if delta := order.FilledSize - leg.Filled; delta > 0 {
+		fill = &QuoteFill{
+			ContractID: leg.ContractID,
+			Side:       leg.Side,
+			Mid:        order.Mid,
+			Price:      lastFillPrice(order, int64(leg.Price)),
+			Size:       delta,
+			Remaining:  order.Size,
+		}
+		leg.Filled = order.FilledSize
+	}
+	leg.Size = order.Size
+	if order.Done {
+		delete(q.byClientID, order.ClientOrderID)
+		q.tracker.Forget(order.ClientOrderID)
+		leg.Mid = ""
+		leg.clientOrderID = ""
+		leg.Size = 0
+	}
+	subscribers := q.subscribers
+	q.mu.Unlock()
+
+	if fill != nil {
+		for _, fn := range subscribers {
+			fn(*fill)
+		}
+	}
+}
+
+func lastFillPrice(order TrackedOrder, fallback int64) int64 {
+	for i := len(order.Reports) - 1; i >= 0; i-- {
+		if r := order.Reports[i]; r.StatusType == StatusCodeTradeOccured && r.FilledPrice != 0 {
+			return r.FilledPrice
+		}
+	}
+	return fallback
+}
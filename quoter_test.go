@@ -0,0 +1,162 @@
+package ledgerx_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/payaaam/go-ledgerx"
+	"github.com/payaaam/go-ledgerx/ledgerxtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func connectedQuoter(t *testing.T, opts ...ledgerx.Option) (*ledgerxtest.MockExchange, *ledgerx.LedgerX, *ledgerx.Quoter) {
+	mock := ledgerxtest.NewMockExchange()
+	t.Cleanup(mock.Close)
+
+	client := mock.Client(opts...)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Error connecting to mock exchange: %s", err.Error())
+	}
+	t.Cleanup(func() { client.Close() })
+	return mock, client, ledgerx.NewQuoter(client)
+}
+
+func TestQuoterPlacesAndAmendsQuotes(t *testing.T) {
+	mock, _, quoter := connectedQuoter(t)
+
+	err := quoter.SetQuote(1, ledgerx.Quote{Price: 100, Size: 5}, ledgerx.Quote{Price: 120, Size: 3})
+	assert.Nil(t, err)
+
+	orders := mock.OpenOrders()
+	if assert.Len(t, orders, 2) {
+		assert.False(t, orders[0].IsAsk)
+		assert.Equal(t, int64(100), orders[0].InsertedPrice)
+		assert.Equal(t, int64(5), orders[0].InsertedSize)
+		assert.True(t, orders[1].IsAsk)
+		assert.Equal(t, int64(120), orders[1].InsertedPrice)
+	}
+	bid, _ := quoter.State(1, ledgerx.SideBid)
+	assert.Equal(t, orders[0].Mid, bid.Mid)
+
+	err = quoter.SetQuote(1, ledgerx.Quote{Price: 101, Size: 5}, ledgerx.Quote{Price: 120, Size: 3})
+	assert.Nil(t, err)
+
+	orders = mock.OpenOrders()
+	if assert.Len(t, orders, 2) {
+		assert.Equal(t, bid.Mid, orders[0].Mid, "amend should keep the resting order")
+		assert.Equal(t, int64(101), orders[0].InsertedPrice)
+	}
+
+	// An unchanged target sends nothing.
+	requests := len(mock.Requests())
+	assert.Nil(t, quoter.SetQuote(1, ledgerx.Quote{Price: 101, Size: 5}, ledgerx.Quote{Price: 120, Size: 3}))
+	assert.Equal(t, requests, len(mock.Requests()))
+
+	// A zero size pulls that side.
+	assert.Nil(t, quoter.SetQuote(1, ledgerx.Quote{Price: 101, Size: 5}, ledgerx.Quote{}))
+	orders = mock.OpenOrders()
+	if assert.Len(t, orders, 1) {
+		assert.False(t, orders[0].IsAsk)
+	}
+
+	assert.Contains(t, mock.Requests(), "POST /api/orders/"+bid.Mid+"/edit")
+}
+
+func TestQuoterTracksFills(t *testing.T) {
+	mock, _, quoter := connectedQuoter(t)
+
+	var mu sync.Mutex
+	var fills []ledgerx.QuoteFill
+	quoter.OnFill(func(f ledgerx.QuoteFill) {
+		mu.Lock()
+		defer mu.Unlock()
+		fills = append(fills, f)
+	})
+
+	assert.Nil(t, quoter.SetQuote(7, ledgerx.Quote{Price: 100, Size: 5}, ledgerx.Quote{Price: 120, Size: 5}))
+	ask, _ := quoter.State(7, ledgerx.SideAsk)
+
+	assert.Nil(t, mock.Fill(ask.Mid, 2))
+	assert.Eventually(t, func() bool {
+		state, _ := quoter.State(7, ledgerx.SideAsk)
+		return state.Filled == 2 && state.Size == 3
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, mock.Fill(ask.Mid, 3))
+	assert.Eventually(t, func() bool {
+		state, _ := quoter.State(7, ledgerx.SideAsk)
+		return state.Mid == ""
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []ledgerx.QuoteFill{
+		{ContractID: 7, Side: ledgerx.SideAsk, Mid: ask.Mid, Price: 120, Size: 2, Remaining: 3},
+		{ContractID: 7, Side: ledgerx.SideAsk, Mid: ask.Mid, Price: 120, Size: 3, Remaining: 0},
+	}, fills)
+	mu.Unlock()
+
+	// The filled side is placed again on the next SetQuote.
+	assert.Nil(t, quoter.SetQuote(7, ledgerx.Quote{Price: 100, Size: 5}, ledgerx.Quote{Price: 121, Size: 5}))
+	assert.Len(t, mock.OpenOrders(), 2)
+}
+
+func TestQuoterPullsOnDisconnect(t *testing.T) {
+	mock, _, quoter := connectedQuoter(t)
+
+	assert.Nil(t, quoter.SetQuote(1, ledgerx.Quote{Price: 100, Size: 5}, ledgerx.Quote{Price: 120, Size: 5}))
+	assert.Nil(t, quoter.SetQuote(2, ledgerx.Quote{Price: 200, Size: 1}, ledgerx.Quote{Price: 220, Size: 1}))
+	assert.Len(t, mock.OpenOrders(), 4)
+
+	mock.DropConnections()
+	assert.Eventually(t, func() bool {
+		for _, state := range quoter.Quotes() {
+			if state.Mid != "" || state.Target != (ledgerx.Quote{}) {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Len(t, mock.OpenOrders(), 0)
+}
+
+func TestQuoterPullsOnRiskBreach(t *testing.T) {
+	risk := ledgerx.NewRiskManager(ledgerx.RiskLimits{MaxOrderSize: 10})
+	mock, _, quoter := connectedQuoter(t, ledgerx.WithRiskManager(risk))
+
+	assert.Nil(t, quoter.SetQuote(1, ledgerx.Quote{Price: 100, Size: 5}, ledgerx.Quote{Price: 120, Size: 5}))
+	assert.Len(t, mock.OpenOrders(), 2)
+
+	err := quoter.SetQuote(1, ledgerx.Quote{Price: 100, Size: 50}, ledgerx.Quote{Price: 120, Size: 5})
+	var riskErr *ledgerx.RiskError
+	if assert.True(t, errors.As(err, &riskErr)) {
+		assert.Equal(t, ledgerx.RiskRuleOrderSize, riskErr.Rule)
+	}
+	assert.Len(t, mock.OpenOrders(), 0)
+}
+
+func TestQuoterReplacesOrdersGoneFromExchange(t *testing.T) {
+	mock := ledgerxtest.NewMockExchange()
+	defer mock.Close()
+	// Without a websocket the quoter never hears that its orders are gone.
+	quoter := ledgerx.NewQuoter(mock.Client())
+
+	assert.Nil(t, quoter.SetQuote(1, ledgerx.Quote{Price: 100, Size: 5}, ledgerx.Quote{Price: 120, Size: 5}))
+	bid, _ := quoter.State(1, ledgerx.SideBid)
+	ask, _ := quoter.State(1, ledgerx.SideAsk)
+	assert.Nil(t, mock.Client().CancelOrder(bid.Mid, 1))
+	assert.Nil(t, mock.Client().CancelOrder(ask.Mid, 1))
+
+	assert.Nil(t, quoter.SetQuote(1, ledgerx.Quote{Price: 101, Size: 5}, ledgerx.Quote{}))
+	replaced, _ := quoter.State(1, ledgerx.SideBid)
+	assert.NotEqual(t, bid.Mid, replaced.Mid, "a stale bid should be placed again")
+	pulled, _ := quoter.State(1, ledgerx.SideAsk)
+	assert.Equal(t, "", pulled.Mid, "a stale ask should be cleared")
+
+	orders := mock.OpenOrders()
+	if assert.Len(t, orders, 1) {
+		assert.Equal(t, replaced.Mid, orders[0].Mid)
+		assert.Equal(t, int64(101), orders[0].InsertedPrice)
+	}
+}
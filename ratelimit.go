@@ -0,0 +1,199 @@
+package ledgerx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request is refused by the client-side
+// limiter in fail-fast mode, or the exchange keeps answering HTTP 429.
+var ErrRateLimited = errors.New("ledgerx rate limited")
+
+const (
+	maxRateLimitRetries     = 3
+	defaultRetryAfterPeriod = time.Second
+)
+
+type endpointClass int
+
+const (
+	endpointRead endpointClass = iota
+	endpointOrder
+)
+
+// RateLimit is a token bucket budget. A zero Rate disables limiting.
+type RateLimit struct {
+	Rate  float64 // requests per second
+	Burst int
+}
+
+// RateLimits configures separate budgets for order entry (create, cancel,
+// replace) and read endpoints. With FailFast set, requests over budget
+// return ErrRateLimited instead of waiting for a token.
+type RateLimits struct {
+	Orders   RateLimit
+	Reads    RateLimit
+	FailFast bool
+}
+
+// WithRateLimits throttles REST requests. Buckets are paused for the
+// Retry-After period of an HTTP 429 so other requests wait as well.
+func WithRateLimits(limits RateLimits) Option {
+	return func(l *LedgerX) {
+		l.limiter = &rateLimiter{
+			orders:   newTokenBucket(limits.Orders),
+			reads:    newTokenBucket(limits.Reads),
+			failFast: limits.FailFast,
+		}
+	}
+}
+
+type rateLimiter struct {
+	orders   *tokenBucket
+	reads    *tokenBucket
+	failFast bool
+}
+
+func (r *rateLimiter) bucket(class endpointClass) *tokenBucket {
+	if r == nil {
+		return nil
+	}
+	if class == endpointOrder {
+		return r.orders
+	}
+	return r.reads
+}
+
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it. In fail-fast mode no token is taken if a wait would be needed.
+func (b *tokenBucket) reserve(failFast bool) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	var wait time.Duration
+	if now.Before(b.pausedUntil) {
+		wait = b.pausedUntil.Sub(now)
+	}
+	if b.tokens < 1 {
+		if w := time.Duration((1 - b.tokens) / b.rate * float64(time.Second)); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 && failFast {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+func (b *tokenBucket) pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// do executes a request within the budget of its endpoint class. HTTP 429
+// responses are retried after their Retry-After period whether or not a
+// budget is configured for the class.
+func (l *LedgerX) do(req *http.Request, class endpointClass) (*http.Response, error) {
+	bucket := l.limiter.bucket(class)
+	failFast := l.limiter != nil && l.limiter.failFast
+
+	for attempt := 0; ; attempt++ {
+		if bucket != nil {
+			wait, ok := bucket.reserve(failFast)
+			if !ok {
+				return nil, fmt.Errorf("%w: retry in %s", ErrRateLimited, wait)
+			}
+			time.Sleep(wait)
+		}
+
+		resp, err := l.send(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if failFast || attempt+1 >= maxRateLimitRetries || !rewindBody(req) {
+			if bucket != nil {
+				bucket.pause(retryAfter)
+			}
+			return nil, fmt.Errorf("%w: exchange returned 429, retry after %s", ErrRateLimited, retryAfter)
+		}
+		if bucket != nil {
+			bucket.pause(retryAfter)
+		} else {
+			time.Sleep(retryAfter)
+		}
+	}
+}
+
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return defaultRetryAfterPeriod
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultRetryAfterPeriod
+}
@@ -0,0 +1,77 @@
+package ledgerx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitFailFastBudgets(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRateLimits(RateLimits{
+		Orders:   RateLimit{Rate: 0.1, Burst: 1},
+		Reads:    RateLimit{Rate: 0.1, Burst: 2},
+		FailFast: true,
+	}))
+
+	_, err := ledgerClient.ListOpenOrders()
+	assert.Nil(t, err, "first read should be within budget")
+	_, err = ledgerClient.ListOpenOrders()
+	assert.Nil(t, err, "second read should use the burst")
+	_, err = ledgerClient.ListOpenOrders()
+	assert.True(t, errors.Is(err, ErrRateLimited), "third read should be rate limited")
+
+	assert.Nil(t, ledgerClient.CancelOrder("a", 1), "orders should have a separate budget")
+	assert.True(t, errors.Is(ledgerClient.CancelOrder("b", 1), ErrRateLimited))
+}
+
+func TestRateLimitRetriesAfter429(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRateLimits(RateLimits{
+		Orders: RateLimit{Rate: 100, Burst: 10},
+	}))
+
+	assert.Nil(t, ledgerClient.CancelOrder("a", 1), "should retry after 429")
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetriesAfter429WithoutLimiter(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer s.Close()
+
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "")
+	_, err := ledgerClient.ListOpenOrders()
+	assert.True(t, errors.Is(err, ErrRateLimited), "persistent 429 should surface as ErrRateLimited")
+	assert.Equal(t, maxRateLimitRetries, calls)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Equal(t, defaultRetryAfterPeriod, parseRetryAfter(""))
+	assert.Equal(t, defaultRetryAfterPeriod, parseRetryAfter("soon"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)))
+}
@@ -2,9 +2,6 @@ package ledgerx
 
 import (
 	"encoding/json"
-	"fmt"
-	"strings"
-	"time"
 )
 
 type Response struct {
@@ -27,30 +24,30 @@ type TopBookResponse struct {
 }
 
 type ActionReportResponse struct {
-	Type                string `json:"type"`
-	ContractID          int64  `json:"contract_id"`
-	Ask                 int64  `json:"ask"`
-	Bid                 int64  `json:"bid"`
-	Clock               int64  `json:"clock"`
-	CustomerID          int64  `json:"cid"`
-	MarketParticipantID int64  `json:"mpid"`
-	InsertedTime        int64  `json:"inserted_time"`
-	UpdatedTime         int64  `json:"updated_time"`
-	Timestamp           int64  `json:"timestamp"`
-	Price               int64  `json:"price"`
-	OriginalPrice       int64  `json:"original_price"`
-	InsertedPrice       int64  `json:"inserted_price"`
-	FilledPrice         int64  `json:"filled_price"`
-	IsAsk               bool   `json:"is_ask"`
-	IsVolatile          bool   `json:"is_volatile"`
-	Size                int64  `json:"size"`
-	OriginalSize        int64  `json:"original_size"`
-	InsertedSize        int64  `json:"inserted_size"`
-	FilledSize          int64  `json:"filled_size"`
-	OrderType           string `json:"order_type"`
-	MessageID           string `json:"mid"`
-	StatusType          int    `json:"status_type"`
-	StatusReason        int    `json:"status_reason"`
+	Type                string   `json:"type"`
+	ContractID          int64    `json:"contract_id"`
+	Ask                 int64    `json:"ask"`
+	Bid                 int64    `json:"bid"`
+	Clock               int64    `json:"clock"`
+	CustomerID          int64    `json:"cid"`
+	MarketParticipantID int64    `json:"mpid"`
+	InsertedTime        NanoTime `json:"inserted_time"`
+	UpdatedTime         NanoTime `json:"updated_time"`
+	Timestamp           NanoTime `json:"timestamp"`
+	Price               int64    `json:"price"`
+	OriginalPrice       int64    `json:"original_price"`
+	InsertedPrice       int64    `json:"inserted_price"`
+	FilledPrice         int64    `json:"filled_price"`
+	IsAsk               bool     `json:"is_ask"`
+	IsVolatile          bool     `json:"is_volatile"`
+	Size                int64    `json:"size"`
+	OriginalSize        int64    `json:"original_size"`
+	InsertedSize        int64    `json:"inserted_size"`
+	FilledSize          int64    `json:"filled_size"`
+	OrderType           string   `json:"order_type"`
+	MessageID           string   `json:"mid"`
+	StatusType          int      `json:"status_type"`
+	StatusReason        int      `json:"status_reason"`
 }
 
 func (a *ActionReportResponse) String() string {
@@ -58,29 +55,6 @@ func (a *ActionReportResponse) String() string {
 	return string(json)
 }
 
-type LedgerTime struct {
-	time.Time
-}
-
-const ledgerTimeLayout = "2006-01-02 15:04:05+0000"
-
-func (lt *LedgerTime) UnmarshalJSON(b []byte) (err error) {
-	s := strings.Trim(string(b), "\"")
-	if s == "null" {
-		lt.Time = time.Time{}
-		return
-	}
-	lt.Time, err = time.Parse(ledgerTimeLayout, s)
-	return
-}
-
-func (lt *LedgerTime) MarshalJSON() ([]byte, error) {
-	if lt.Time.UnixNano() == (time.Time{}).UnixNano() {
-		return []byte("null"), nil
-	}
-	return []byte(fmt.Sprintf("\"%s\"", lt.Time.Format(ledgerTimeLayout))), nil
-}
-
 type ListContractsData struct {
 	ID              int64      `json:"id"`
 	Label           string     `json:"label"`
@@ -111,28 +85,32 @@ type ListOpenOrdersResponse struct {
 	Metadata []Metadata           `json:"metadata"`
 }
 
+type GetOrderResponse struct {
+	Data ListOpenOrdersData `json:"data"`
+}
+
 type ListOpenOrdersData struct {
-	Mid           string `json:"mid"`
-	Type          string `json:"type"`
-	Mpid          int64  `json:"mpid"`
-	Cid           int64  `json:"cid"`
-	Timestamp     int64  `json:"timestamp"`
-	Ticks         int64  `json:"ticks"`
-	ContractID    int64  `json:"contract_id"`
-	OriginalPrice int64  `json:"orignal_price"`
-	OriginalSize  int64  `json:"orignal_size"`
-	InsertedPrice int64  `json:"inserted_price"`
-	InsertedSize  int64  `json:"inserted_size"`
-	FilledPrice   int64  `json:"filled_price"`
-	FilledSize    int64  `json:"filled_size"`
-	Vwap          int32  `json:"vwap"`
-	StatusType    int32  `json:"status_type"`
-	StatusReason  int32  `json:"status_reason"`
-	IsAsk         bool   `json:"is_ask"`
-	InsertedTime  int64  `json:"inserted_time"`
-	UpdatedTime   int64  `json:"updated_time"`
-	OrderType     string `json:"order_type"`
-	Clock         int64  `json:"clock"`
+	Mid           string   `json:"mid"`
+	Type          string   `json:"type"`
+	Mpid          int64    `json:"mpid"`
+	Cid           int64    `json:"cid"`
+	Timestamp     NanoTime `json:"timestamp"`
+	Ticks         int64    `json:"ticks"`
+	ContractID    int64    `json:"contract_id"`
+	OriginalPrice int64    `json:"original_price"`
+	OriginalSize  int64    `json:"original_size"`
+	InsertedPrice int64    `json:"inserted_price"`
+	InsertedSize  int64    `json:"inserted_size"`
+	FilledPrice   int64    `json:"filled_price"`
+	FilledSize    int64    `json:"filled_size"`
+	Vwap          int32    `json:"vwap"`
+	StatusType    int32    `json:"status_type"`
+	StatusReason  int32    `json:"status_reason"`
+	IsAsk         bool     `json:"is_ask"`
+	InsertedTime  NanoTime `json:"inserted_time"`
+	UpdatedTime   NanoTime `json:"updated_time"`
+	OrderType     string   `json:"order_type"`
+	Clock         int64    `json:"clock"`
 }
 
 type Metadata struct {
@@ -154,28 +132,53 @@ type ListTradesResponse struct {
 }
 
 type ListTradeData struct {
-	ID            int64  `json:"id"`
-	ContractID    int64  `json:"contract_id,string"`
-	ContractLabel string `json:"contract_label"`
-	FilledPrice   int64  `json:"filled_price"`
-	FilledSize    int64  `json:"filled_size"`
-	Fee           int64  `json:"fee"`
-	OrderType     string `json:"order_type"`
-	OrderID       string `json:"order_id"`
-	StatusType    string `json:"status_type"`
-	Created       string `json:"created"`
-	Timestamp     string `json:"timestamp"`
-	Side          string `json:"side"`
+	ID            int64     `json:"id"`
+	ContractID    int64     `json:"contract_id,string"`
+	ContractLabel string    `json:"contract_label"`
+	FilledPrice   int64     `json:"filled_price"`
+	FilledSize    int64     `json:"filled_size"`
+	Fee           int64     `json:"fee"`
+	OrderType     string    `json:"order_type"`
+	OrderID       string    `json:"order_id"`
+	StatusType    string    `json:"status_type"`
+	Created       TradeTime `json:"created"`
+	Timestamp     TradeTime `json:"timestamp"`
+	Side          string    `json:"side"`
+}
+
+type ListPositionsResponse struct {
+	Data     []ListPositionsData `json:"data"`
+	Metadata Metadata            `json:"meta"`
+}
+
+type ListPositionsData struct {
+	ID                  int64             `json:"id"`
+	Type                string            `json:"type"`
+	Size                int64             `json:"size"`
+	AssignedSize        int64             `json:"assigned_size"`
+	ExercisedSize       int64             `json:"exercised_size"`
+	MarketParticipantID int64             `json:"mpid"`
+	Contract            ListContractsData `json:"contract"`
+}
+
+// NetSize returns the position size, negative for short positions.
+func (p ListPositionsData) NetSize() int64 {
+	if p.Type == "short" {
+		return -p.Size
+	}
+	return p.Size
 }
 
 type CreateOrderRequest struct {
-	OrderType   string `json:"order_type"`
-	ContractID  int32  `json:"contract_id"`
-	IsAsk       bool   `json:"is_ask"`
-	SwapPurpose string `json:"swap_purpose"`
-	Size        int32  `json:"size"`
-	Price       int32  `json:"price"`
-	Volatile    bool   `json:"volatile"`
+	OrderType   OrderType   `json:"order_type"`
+	ContractID  int32       `json:"contract_id"`
+	IsAsk       bool        `json:"is_ask"`
+	SwapPurpose SwapPurpose `json:"swap_purpose"`
+	Size        int32       `json:"size"`
+	Price       int32       `json:"price"`
+	Volatile    bool        `json:"volatile"`
+
+	buildErr error // set by Buy and Sell, reported by Validate
 }
 
 func (c *CreateOrderRequest) String() string {
@@ -220,11 +223,11 @@ type OpenPositionsMessage struct {
 }
 
 type HeartbeatMessage struct {
-	Type       string `json:"type"`
-	Timestamp  int64  `json:"timestamp"`
-	Ticks      int64  `json:"ticks"`
-	RunID      int64  `json:"run_id"`
-	IntervalMS int64  `json:"interval_ms"`
+	Type       string   `json:"type"`
+	Timestamp  NanoTime `json:"timestamp"`
+	Ticks      int64    `json:"ticks"`
+	RunID      int64    `json:"run_id"`
+	IntervalMS int64    `json:"interval_ms"`
 }
 
 func (o *OpenPositionsMessage) String() string {
@@ -256,9 +259,12 @@ type Collateral struct {
 	PositionLockedBalances    LedgerBalance `json:"position_locked_balances"`
 }
 
-type LedgerBalance struct {
-	BTC  int64 `json:"BTC"`
-	CBTC int64 `json:"CBTC"`
-	USD  int64 `json:"USD"`
-	ETH  int64 `json:"ETH"`
+func (c Collateral) balances() []LedgerBalance {
+	return []LedgerBalance{
+		c.AvailableBalances,
+		c.DeliverableLockedBalances,
+		c.FeeLockedBalances,
+		c.OrderLockedBalances,
+		c.PositionLockedBalances,
+	}
 }
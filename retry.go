@@ -0,0 +1,211 @@
+package ledgerx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrOrderOutcomeUnknown is returned by CreateOrder when a submit failed in
+// transit and the exchange's state does not show whether the order was
+// accepted. Resubmitting could double the order, so reconcile with
+// LookupOrder or the trade history before trying again.
+var ErrOrderOutcomeUnknown = errors.New("ledgerx order outcome unknown")
+
+// RetryPolicy retries transient failures with exponential backoff.
+// Idempotent reads are retried on transport errors and 5xx responses.
+// CreateOrder is only retried after a transport error, and only once
+// neither the open orders nor the trades since the first attempt show a
+// matching order. Orders whose mids were already returned by CreateOrder
+// are never matched. If more than one order matches, or a match could have
+// come from a concurrent identical submit, CreateOrder returns
+// ErrOrderOutcomeUnknown instead of guessing. An order cancelled without
+// any fill before the check leaves no trace and is resubmitted.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// orderMatchSkew allows for clock differences between the client and the
+// exchange when matching an order's inserted time against the submit time.
+const orderMatchSkew = time.Second
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(l *LedgerX) {
+		l.retry = &policy
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (p *RetryPolicy) retryRead(resp *http.Response, err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrRateLimited)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+func (p *RetryPolicy) retryOrder(err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	return !errors.Is(err, ErrRateLimited)
+}
+
+func (l *LedgerX) doRead(req *http.Request) (*http.Response, error) {
+	resp, err := l.do(req, endpointRead)
+	for attempt := 1; l.retry.retryRead(resp, err, attempt); attempt++ {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		time.Sleep(l.retry.backoff(attempt))
+		resp, err = l.do(req, endpointRead)
+	}
+	return resp, err
+}
+
+// findSubmittedOrder looks for an order matching request that was inserted
+// after submitted and not claimed by another CreateOrder call, returning its
+// mid. Fully filled orders are found through their trades.
+func (l *LedgerX) findSubmittedOrder(request *CreateOrderRequest, submitted time.Time) (string, bool, error) {
+	after := submitted.Add(-orderMatchSkew)
+	candidates := make(map[string]bool)
+
+	openOrders, err := l.ListOpenOrders()
+	if err != nil {
+		return "", false, err
+	}
+	for _, order := range openOrders.Data {
+		if matchesSubmitted(order, request, after) && !l.submits.claimed(order.Mid) {
+			candidates[order.Mid] = true
+		}
+	}
+
+	side := request.Side()
+	trades, err := l.tradesSince(after, func(t ListTradeData) bool {
+		tradeSide, ok := tradeSide(t)
+		return ok && tradeSide == side && t.ContractID == int64(request.ContractID)
+	})
+	if err != nil {
+		return "", false, err
+	}
+	checked := make(map[string]bool)
+	for _, t := range trades {
+		if checked[t.OrderID] || candidates[t.OrderID] || l.submits.claimed(t.OrderID) {
+			continue
+		}
+		checked[t.OrderID] = true
+		order, err := l.GetOrder(t.OrderID)
+		if err != nil {
+			return "", false, fmt.Errorf("checking order %s of trade %d: %w", t.OrderID, t.ID, err)
+		}
+		if matchesSubmitted(order.Data, request, after) {
+			candidates[t.OrderID] = true
+		}
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return "", false, nil
+	case len(candidates) > 1:
+		return "", false, fmt.Errorf("%w: %d matching orders", ErrOrderOutcomeUnknown, len(candidates))
+	case l.submits.concurrent(request):
+		return "", false, fmt.Errorf("%w: an identical order was submitted concurrently", ErrOrderOutcomeUnknown)
+	}
+	for mid := range candidates {
+		return mid, true, nil
+	}
+	return "", false, nil
+}
+
+func matchesSubmitted(order ListOpenOrdersData, request *CreateOrderRequest, after time.Time) bool {
+	price, size := order.OriginalPrice, order.OriginalSize
+	if price == 0 && size == 0 {
+		price, size = order.InsertedPrice, order.InsertedSize
+	}
+	return order.ContractID == int64(request.ContractID) &&
+		order.IsAsk == request.IsAsk &&
+		price == int64(request.Price) &&
+		size == int64(request.Size) &&
+		!order.InsertedTime.Before(after)
+}
+
+// submitLog tracks CreateOrder calls in progress and the mids they returned,
+// so that reconciling a failed submit never claims another call's order.
+type submitLog struct {
+	mu       sync.Mutex
+	inFlight map[CreateOrderRequest]int
+	mids     map[string]time.Time
+}
+
+// submitClaimTTL is how long returned mids are remembered, well beyond the
+// window in which a retry can look for its order.
+const submitClaimTTL = 5 * time.Minute
+
+func (s *submitLog) begin(request *CreateOrderRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight == nil {
+		s.inFlight = make(map[CreateOrderRequest]int)
+	}
+	s.inFlight[*request]++
+}
+
+func (s *submitLog) end(request *CreateOrderRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[*request]--; s.inFlight[*request] <= 0 {
+		delete(s.inFlight, *request)
+	}
+}
+
+// concurrent reports whether another submit of an identical request is in
+// progress.
+func (s *submitLog) concurrent(request *CreateOrderRequest) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight[*request] > 1
+}
+
+func (s *submitLog) claim(mid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.mids == nil {
+		s.mids = make(map[string]time.Time)
+	}
+	for m, at := range s.mids {
+		if now.Sub(at) > submitClaimTTL {
+			delete(s.mids, m)
+		}
+	}
+	s.mids[mid] = now
+}
+
+func (s *submitLog) claimed(mid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.mids[mid]
+	return ok
+}
@@ -0,0 +1,222 @@
+package ledgerx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+// flakyClient fails the first failures requests for each path with a
+// transport error, after executing them against next when deliver is set.
+type flakyClient struct {
+	next     clientInterface
+	failures map[string]int
+	deliver  bool
+	calls    map[string]int
+}
+
+func (c *flakyClient) Do(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.Path
+	c.calls[key]++
+	if c.failures[key] > 0 {
+		c.failures[key]--
+		if c.deliver {
+			resp, err := c.next.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+		return nil, errors.New("connection reset")
+	}
+	return c.next.Do(req)
+}
+
+func TestRetryIdempotentReads(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"mid": "a"}]}`))
+	}))
+	defer s.Close()
+
+	client := &flakyClient{
+		next:     http.DefaultClient,
+		failures: map[string]int{"GET /api/open-orders": 2},
+		calls:    map[string]int{},
+	}
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRetryPolicy(testRetryPolicy))
+	ledgerClient.client = client
+
+	resp, err := ledgerClient.ListOpenOrders()
+	assert.Nil(t, err, "should succeed after retries")
+	assert.Equal(t, "a", resp.Data[0].Mid)
+	assert.Equal(t, 3, client.calls["GET /api/open-orders"])
+}
+
+func TestCreateOrderRetryFindsSubmittedOrder(t *testing.T) {
+	var orders []ListOpenOrdersData
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/orders":
+			var request CreateOrderRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			orders = append(orders, ListOpenOrdersData{
+				Mid:           "submitted",
+				ContractID:    int64(request.ContractID),
+				IsAsk:         request.IsAsk,
+				InsertedPrice: int64(request.Price),
+				InsertedSize:  int64(request.Size),
+				InsertedTime:  NanoTimeOf(time.Now()),
+			})
+			w.Write([]byte(`{"data": {"mid": "submitted"}}`))
+		case "/api/open-orders":
+			json.NewEncoder(w).Encode(&ListOpenOrdersResponse{Data: orders})
+		case "/trading/trades":
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer s.Close()
+
+	client := &flakyClient{
+		next:     http.DefaultClient,
+		failures: map[string]int{"POST /api/orders": 1},
+		deliver:  true,
+		calls:    map[string]int{},
+	}
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRetryPolicy(testRetryPolicy))
+	ledgerClient.client = client
+
+	resp, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 2, Price: 300})
+	assert.Nil(t, err, "should recover the submitted order")
+	assert.Equal(t, "submitted", resp.Data.Mid)
+	assert.Equal(t, 1, client.calls["POST /api/orders"], "should not submit the order twice")
+	assert.Len(t, orders, 1)
+}
+
+func TestCreateOrderRetryResubmitsLostOrder(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/orders":
+			w.Write([]byte(`{"data": {"mid": "second"}}`))
+		case "/api/open-orders", "/trading/trades":
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer s.Close()
+
+	client := &flakyClient{
+		next:     http.DefaultClient,
+		failures: map[string]int{"POST /api/orders": 1},
+		calls:    map[string]int{},
+	}
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRetryPolicy(testRetryPolicy))
+	ledgerClient.client = client
+
+	resp, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 2, Price: 300})
+	assert.Nil(t, err, "should resubmit a lost order")
+	assert.Equal(t, "second", resp.Data.Mid)
+	assert.Equal(t, 2, client.calls["POST /api/orders"])
+}
+
+func TestCreateOrderRetryFindsFilledOrder(t *testing.T) {
+	filled := ListOpenOrdersData{Mid: "filled", ContractID: 1, OriginalPrice: 300, OriginalSize: 2, InsertedTime: NanoTimeOf(time.Now())}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/orders":
+			w.Write([]byte(`{"data": {"mid": "filled"}}`))
+		case "/api/open-orders":
+			w.Write([]byte(`{"data": []}`))
+		case "/trading/trades":
+			json.NewEncoder(w).Encode(&ListTradesResponse{Data: []ListTradeData{
+				{ID: 1, ContractID: 1, OrderID: "filled", Side: "bid", FilledSize: 2, Timestamp: TradeTimeOf(time.Now())},
+			}})
+		case "/api/orders/filled":
+			json.NewEncoder(w).Encode(&GetOrderResponse{Data: filled})
+		}
+	}))
+	defer s.Close()
+
+	client := &flakyClient{
+		next:     http.DefaultClient,
+		failures: map[string]int{"POST /api/orders": 1},
+		deliver:  true,
+		calls:    map[string]int{},
+	}
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRetryPolicy(testRetryPolicy))
+	ledgerClient.client = client
+
+	resp, err := ledgerClient.CreateOrder(Buy(1, 2).Limit(300))
+	assert.Nil(t, err, "should find the order through its trades")
+	assert.Equal(t, "filled", resp.Data.Mid)
+	assert.Equal(t, 1, client.calls["POST /api/orders"], "should not resubmit a filled order")
+}
+
+func TestCreateOrderRetryAmbiguousMatch(t *testing.T) {
+	now := NanoTimeOf(time.Now())
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/open-orders":
+			json.NewEncoder(w).Encode(&ListOpenOrdersResponse{Data: []ListOpenOrdersData{
+				{Mid: "a", ContractID: 1, InsertedPrice: 300, InsertedSize: 2, InsertedTime: now},
+				{Mid: "b", ContractID: 1, InsertedPrice: 300, InsertedSize: 2, InsertedTime: now},
+			}})
+		case "/trading/trades":
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer s.Close()
+
+	client := &flakyClient{
+		next:     http.DefaultClient,
+		failures: map[string]int{"POST /api/orders": 1},
+		calls:    map[string]int{},
+	}
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRetryPolicy(testRetryPolicy))
+	ledgerClient.client = client
+
+	_, err := ledgerClient.CreateOrder(Buy(1, 2).Limit(300))
+	assert.True(t, errors.Is(err, ErrOrderOutcomeUnknown), "should not guess between matching orders")
+	assert.Equal(t, 1, client.calls["POST /api/orders"])
+}
+
+func TestCreateOrderRetrySkipsClaimedOrders(t *testing.T) {
+	var orders []ListOpenOrdersData
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/orders":
+			mid := fmt.Sprintf("order-%d", len(orders)+1)
+			orders = append(orders, ListOpenOrdersData{Mid: mid, ContractID: 1, InsertedPrice: 300, InsertedSize: 2, InsertedTime: NanoTimeOf(time.Now())})
+			json.NewEncoder(w).Encode(&CreateOrderResponse{Data: CreateOrderData{Mid: mid}})
+		case "/api/open-orders":
+			json.NewEncoder(w).Encode(&ListOpenOrdersResponse{Data: orders})
+		case "/trading/trades":
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer s.Close()
+
+	client := &flakyClient{next: http.DefaultClient, failures: map[string]int{}, calls: map[string]int{}}
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithRetryPolicy(testRetryPolicy))
+	ledgerClient.client = client
+
+	first, err := ledgerClient.CreateOrder(Buy(1, 2).Limit(300))
+	assert.Nil(t, err)
+
+	client.failures["POST /api/orders"] = 1
+	second, err := ledgerClient.CreateOrder(Buy(1, 2).Limit(300))
+	assert.Nil(t, err, "the lost submit should be resubmitted")
+	assert.NotEqual(t, first.Data.Mid, second.Data.Mid, "should not claim the earlier order")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 300*time.Millisecond, policy.backoff(3))
+}
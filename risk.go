@@ -0,0 +1,356 @@
+package ledgerx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RiskLimits configures the pre-trade checks applied by a RiskManager.
+// A zero value disables the corresponding check. Prices and notionals are
+// in cents, matching the wire format of CreateOrderRequest. Market orders
+// carry no price: when MaxNotional or CheckCollateral is set they are
+// valued at the opposite side of the last top of book seen for the
+// contract, and rejected if there is none.
+type RiskLimits struct {
+	MaxOrderSize    int64 // contracts per order
+	MaxNotional     int64 // resting price * size per contract, including the new order
+	MaxOpenOrders   int   // resting orders across all contracts
+	MaxNetPosition  int64 // absolute net position per underlying asset
+	CheckCollateral bool  // reject bids exceeding available USD collateral
+}
+
+type RiskRule string
+
+const (
+	RiskRuleOrderSize    RiskRule = "max_order_size"
+	RiskRuleNotional     RiskRule = "max_notional"
+	RiskRuleOpenOrders   RiskRule = "max_open_orders"
+	RiskRuleNetPosition  RiskRule = "max_net_position"
+	RiskRuleCollateral   RiskRule = "available_collateral"
+	RiskRuleMarketPrice  RiskRule = "market_price_unknown"
+	RiskRuleUnknownOrder RiskRule = "unknown_order"
+)
+
+// RiskError is returned when an order would breach a configured limit.
+type RiskError struct {
+	Rule       RiskRule
+	ContractID int64
+	Value      int64
+	Limit      int64
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("ledgerx risk limit breached: %s (contract %d, value %d, limit %d)", e.Rule, e.ContractID, e.Value, e.Limit)
+}
+
+type riskOrder struct {
+	contractID int64
+	price      int64
+	size       int64
+	isAsk      bool
+}
+
+func (o riskOrder) notional() int64 {
+	return o.price * o.size
+}
+
+// RiskManager enforces RiskLimits using open orders, positions and
+// collateral learned from the websocket feed. Register it with
+// WithRiskManager to have the client check every order it sends. Orders
+// the client is submitting are reserved against the limits until their
+// first action report, so concurrent submits cannot together exceed them.
+// Amendments of orders it does not know about, such as orders placed
+// before it was attached, are rejected; call SyncOpenOrders to adopt them.
+type RiskManager struct {
+	limits RiskLimits
+
+	mu         sync.RWMutex
+	orders     map[string]riskOrder
+	reserved   map[int]riskOrder
+	nextID     int
+	early      earlyReports // reports received while reservations are held
+	positions  map[int64]int64
+	books      map[int64]TopBookResponse
+	contracts  map[int64]ListContractsData
+	lookup     ContractLookup
+	available  LedgerBalance
+	hasBalance bool
+}
+
+func NewRiskManager(limits RiskLimits) *RiskManager {
+	return &RiskManager{
+		limits:    limits,
+		orders:    make(map[string]riskOrder),
+		reserved:  make(map[int]riskOrder),
+		positions: make(map[int64]int64),
+		books:     make(map[int64]TopBookResponse),
+		contracts: make(map[int64]ListContractsData),
+	}
+}
+
+// SetContracts provides the contract metadata used to group positions by
+// underlying asset. Contracts without metadata are treated as their own
+// underlying.
+func (r *RiskManager) SetContracts(contracts []ListContractsData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range contracts {
+		r.contracts[c.ID] = c
+	}
+}
+
+// SetContractLookup resolves contracts missing from SetContracts, typically
+// through a ContractRegistry.
+func (r *RiskManager) SetContractLookup(lookup ContractLookup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookup = lookup
+}
+
+// SyncOpenOrders replaces the tracked open orders with a REST snapshot.
+func (r *RiskManager) SyncOpenOrders(resp *ListOpenOrdersResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders = make(map[string]riskOrder, len(resp.Data))
+	for _, o := range resp.Data {
+		r.orders[o.Mid] = riskOrder{
+			contractID: o.ContractID,
+			price:      o.InsertedPrice,
+			size:       o.InsertedSize - o.FilledSize,
+			isAsk:      o.IsAsk,
+		}
+	}
+}
+
+func (r *RiskManager) HandleMessage(msg Message) {
+	switch data := msg.Data.(type) {
+	case ActionReportResponse:
+		r.applyActionReport(data)
+	case OpenPositionsMessage:
+		r.mu.Lock()
+		for _, p := range data.Positions {
+			r.positions[p.ContractID] = p.Size
+		}
+		r.mu.Unlock()
+	case BalanceUpdateMessage:
+		r.mu.Lock()
+		r.available = data.Collateral.AvailableBalances
+		r.hasBalance = true
+		r.mu.Unlock()
+	case TopBookResponse:
+		r.mu.Lock()
+		r.books[data.ContractID] = data
+		r.mu.Unlock()
+	}
+}
+
+func (r *RiskManager) applyActionReport(report ActionReportResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.orders[report.MessageID]; !ok {
+		r.early.add(report, time.Now())
+	}
+
+	switch report.StatusType {
+	case StatusCodeOrderInserted, StatusCodeTradeOccured, StatusCodeOrderCancelledAndReplaced:
+		if report.Size <= 0 {
+			delete(r.orders, report.MessageID)
+			return
+		}
+		r.orders[report.MessageID] = riskOrder{
+			contractID: report.ContractID,
+			price:      report.Price,
+			size:       report.Size,
+			isAsk:      report.IsAsk,
+		}
+	case StatusCodeOrderCancelled, StatusCodeMarketOrderNotFilled:
+		delete(r.orders, report.MessageID)
+	}
+}
+
+// CheckOrder validates a new order against every configured limit.
+func (r *RiskManager) CheckOrder(request *CreateOrderRequest) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	order, err := r.newOrder(request)
+	if err != nil {
+		return err
+	}
+	if err := r.check(order, "", true); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newOrder must be called with r.mu held. Market orders are priced against
+// the top of book when a limit needs their value.
+func (r *RiskManager) newOrder(request *CreateOrderRequest) (riskOrder, *RiskError) {
+	order := riskOrder{
+		contractID: int64(request.ContractID),
+		price:      int64(request.Price),
+		size:       int64(request.Size),
+		isAsk:      request.IsAsk,
+	}
+	if request.OrderType != OrderTypeMarket || (r.limits.MaxNotional <= 0 && !r.limits.CheckCollateral) {
+		return order, nil
+	}
+	book := r.books[order.contractID]
+	order.price = book.Ask
+	if order.isAsk {
+		order.price = book.Bid
+	}
+	if order.price <= 0 {
+		return order, &RiskError{Rule: RiskRuleMarketPrice, ContractID: order.contractID}
+	}
+	return order, nil
+}
+
+// reserveOrder checks a new order like CheckOrder and, if it passes, holds
+// its exposure until settleReservation.
+func (r *RiskManager) reserveOrder(request *CreateOrderRequest) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, err := r.newOrder(request)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.check(order, "", true); err != nil {
+		return 0, err
+	}
+	r.nextID++
+	r.reserved[r.nextID] = order
+	r.early.begin()
+	return r.nextID, nil
+}
+
+// settleReservation releases a reservation once its submit has returned.
+// An accepted order is tracked under its mid until action reports update
+// it, unless a report for the mid has already arrived. A submit whose
+// outcome is unknown is released too, as it cannot be matched to reports.
+func (r *RiskManager) settleReservation(id int, mid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.reserved[id]
+	if !ok {
+		return
+	}
+	delete(r.reserved, id)
+	if reported := r.early.end(mid); mid != "" && len(reported) == 0 {
+		r.orders[mid] = order
+	}
+}
+
+// CheckReplace validates an amendment of a resting order. The replaced
+// order's exposure is excluded from the notional and collateral checks.
+// Orders the manager does not know are rejected, as their side and
+// exposure are unknown.
+func (r *RiskManager) CheckReplace(mid string, request *CancelAndReplaceRequest) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	existing, ok := r.orders[mid]
+	if !ok {
+		return &RiskError{Rule: RiskRuleUnknownOrder, ContractID: int64(request.ContractID)}
+	}
+	order := riskOrder{
+		contractID: int64(request.ContractID),
+		price:      int64(request.Price),
+		size:       int64(request.Size) - existing.size,
+		isAsk:      existing.isAsk,
+	}
+	if err := r.check(order, mid, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// check must be called with r.mu held. The size of order is the change in
+// exposure it represents, which for a replacement may be negative.
+func (r *RiskManager) check(order riskOrder, replacing string, isNew bool) *RiskError {
+	l := r.limits
+	orderSize := order.size
+	if !isNew {
+		orderSize += r.orders[replacing].size
+	}
+
+	if l.MaxOrderSize > 0 && orderSize > l.MaxOrderSize {
+		return &RiskError{Rule: RiskRuleOrderSize, ContractID: order.contractID, Value: orderSize, Limit: l.MaxOrderSize}
+	}
+
+	newNotional := order.price * orderSize
+	if l.MaxNotional > 0 {
+		notional := newNotional
+		for mid, o := range r.orders {
+			if o.contractID == order.contractID && mid != replacing {
+				notional += o.notional()
+			}
+		}
+		for _, o := range r.reserved {
+			if o.contractID == order.contractID {
+				notional += o.notional()
+			}
+		}
+		if notional > l.MaxNotional {
+			return &RiskError{Rule: RiskRuleNotional, ContractID: order.contractID, Value: notional, Limit: l.MaxNotional}
+		}
+	}
+
+	if open := len(r.orders) + len(r.reserved) + 1; isNew && l.MaxOpenOrders > 0 && open > l.MaxOpenOrders {
+		return &RiskError{Rule: RiskRuleOpenOrders, ContractID: order.contractID, Value: int64(open), Limit: int64(l.MaxOpenOrders)}
+	}
+
+	if l.MaxNetPosition > 0 {
+		net := r.netPosition(r.underlying(order.contractID))
+		if order.isAsk {
+			net -= order.size
+		} else {
+			net += order.size
+		}
+		if abs(net) > l.MaxNetPosition {
+			return &RiskError{Rule: RiskRuleNetPosition, ContractID: order.contractID, Value: net, Limit: l.MaxNetPosition}
+		}
+	}
+
+	if l.CheckCollateral && r.hasBalance && !order.isAsk {
+		required := order.price * order.size
+		for _, o := range r.reserved {
+			if !o.isAsk {
+				required += o.notional()
+			}
+		}
+		if required > r.available.USD {
+			return &RiskError{Rule: RiskRuleCollateral, ContractID: order.contractID, Value: required, Limit: r.available.USD}
+		}
+	}
+
+	return nil
+}
+
+func (r *RiskManager) underlying(contractID int64) string {
+	if c, ok := r.contracts[contractID]; ok && c.UnderlyingAsset != "" {
+		return c.UnderlyingAsset
+	}
+	if r.lookup != nil {
+		if c, ok := r.lookup.Contract(contractID); ok && c.UnderlyingAsset != "" {
+			return c.UnderlyingAsset
+		}
+	}
+	return fmt.Sprintf("contract:%d", contractID)
+}
+
+func (r *RiskManager) netPosition(underlying string) int64 {
+	var net int64
+	for contractID, size := range r.positions {
+		if r.underlying(contractID) == underlying {
+			net += size
+		}
+	}
+	return net
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,138 @@
+package ledgerx
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRiskManagerLimits(t *testing.T) {
+	risk := NewRiskManager(RiskLimits{
+		MaxOrderSize:    10,
+		MaxNotional:     50000,
+		MaxOpenOrders:   2,
+		MaxNetPosition:  15,
+		CheckCollateral: true,
+	})
+	risk.SetContracts([]ListContractsData{
+		{ID: 1, UnderlyingAsset: "CBTC"},
+		{ID: 2, UnderlyingAsset: "CBTC"},
+	})
+	risk.HandleMessage(Message{Type: ChanBalanceUpdate, Data: BalanceUpdateMessage{
+		Collateral: Collateral{AvailableBalances: LedgerBalance{USD: 40000}},
+	}})
+	risk.HandleMessage(Message{Type: ChanOpenPositionsUpdate, Data: OpenPositionsMessage{
+		Positions: []Position{{ContractID: 1, Size: 5}, {ContractID: 2, Size: 4}},
+	}})
+
+	assert.Nil(t, risk.CheckOrder(&CreateOrderRequest{ContractID: 1, Size: 5, Price: 1000}))
+
+	cases := []struct {
+		request *CreateOrderRequest
+		rule    RiskRule
+	}{
+		{&CreateOrderRequest{ContractID: 1, Size: 11, Price: 100}, RiskRuleOrderSize},
+		{&CreateOrderRequest{ContractID: 1, Size: 10, Price: 6000}, RiskRuleNotional},
+		{&CreateOrderRequest{ContractID: 2, Size: 7, Price: 100}, RiskRuleNetPosition},
+		{&CreateOrderRequest{ContractID: 1, Size: 5, Price: 9000}, RiskRuleCollateral},
+	}
+	for _, c := range cases {
+		err := risk.CheckOrder(c.request)
+		var riskErr *RiskError
+		if assert.True(t, errors.As(err, &riskErr), "expected risk error for %s", c.rule) {
+			assert.Equal(t, c.rule, riskErr.Rule)
+		}
+	}
+
+	// Asks reduce the long position and are allowed.
+	assert.Nil(t, risk.CheckOrder(&CreateOrderRequest{ContractID: 2, Size: 7, Price: 100, IsAsk: true}))
+
+	for _, mid := range []string{"a", "b"} {
+		risk.HandleMessage(Message{Type: ChanActionReport, Data: ActionReportResponse{
+			MessageID:  mid,
+			ContractID: 1,
+			Price:      2000,
+			Size:       10,
+			StatusType: StatusCodeOrderInserted,
+		}})
+	}
+	err := risk.CheckOrder(&CreateOrderRequest{ContractID: 2, Size: 1, Price: 100, IsAsk: true})
+	var riskErr *RiskError
+	if assert.True(t, errors.As(err, &riskErr)) {
+		assert.Equal(t, RiskRuleOpenOrders, riskErr.Rule)
+	}
+
+	// Replacing a resting order excludes its own notional.
+	assert.Nil(t, risk.CheckReplace("a", &CancelAndReplaceRequest{ContractID: 1, Size: 10, Price: 3000}))
+	assert.NotNil(t, risk.CheckReplace("a", &CancelAndReplaceRequest{ContractID: 1, Size: 10, Price: 3500}))
+
+	risk.HandleMessage(Message{Type: ChanActionReport, Data: ActionReportResponse{
+		MessageID:  "b",
+		StatusType: StatusCodeOrderCancelled,
+	}})
+	assert.Nil(t, risk.CheckOrder(&CreateOrderRequest{ContractID: 2, Size: 1, Price: 100, IsAsk: true}))
+}
+
+func TestRiskManagerRejectsUnknownReplace(t *testing.T) {
+	risk := NewRiskManager(RiskLimits{MaxNetPosition: 5})
+	err := risk.CheckReplace("unknown", &CancelAndReplaceRequest{ContractID: 1, Size: 1, Price: 100})
+	var riskErr *RiskError
+	if assert.True(t, errors.As(err, &riskErr), "an order of unknown side should not be guessed at") {
+		assert.Equal(t, RiskRuleUnknownOrder, riskErr.Rule)
+	}
+
+	risk.SyncOpenOrders(&ListOpenOrdersResponse{Data: []ListOpenOrdersData{
+		{Mid: "unknown", ContractID: 1, InsertedPrice: 100, InsertedSize: 4, IsAsk: true},
+	}})
+	assert.Nil(t, risk.CheckReplace("unknown", &CancelAndReplaceRequest{ContractID: 1, Size: 5, Price: 100}))
+}
+
+func TestRiskManagerPricesMarketOrders(t *testing.T) {
+	risk := NewRiskManager(RiskLimits{MaxNotional: 10000, CheckCollateral: true})
+	risk.HandleMessage(Message{Type: ChanBalanceUpdate, Data: BalanceUpdateMessage{
+		Collateral: Collateral{AvailableBalances: LedgerBalance{USD: 4000}},
+	}})
+
+	err := risk.CheckOrder(Buy(1, 5).Market())
+	var riskErr *RiskError
+	if assert.True(t, errors.As(err, &riskErr), "market orders need a price to check") {
+		assert.Equal(t, RiskRuleMarketPrice, riskErr.Rule)
+	}
+
+	risk.HandleMessage(Message{Data: TopBookResponse{ContractID: 1, Bid: 500, Ask: 1000}})
+	err = risk.CheckOrder(Buy(1, 5).Market())
+	if assert.True(t, errors.As(err, &riskErr)) {
+		assert.Equal(t, RiskRuleCollateral, riskErr.Rule)
+		assert.Equal(t, int64(5000), riskErr.Value, "bids should be valued at the ask")
+	}
+	err = risk.CheckOrder(Sell(1, 30).Market())
+	if assert.True(t, errors.As(err, &riskErr)) {
+		assert.Equal(t, RiskRuleNotional, riskErr.Rule)
+		assert.Equal(t, int64(15000), riskErr.Value, "asks should be valued at the bid")
+	}
+	assert.Nil(t, risk.CheckOrder(Buy(1, 4).Market()))
+
+	assert.Nil(t, NewRiskManager(RiskLimits{MaxOrderSize: 10}).CheckOrder(Buy(1, 5).Market()), "other limits need no price")
+}
+
+type failingClient struct {
+	calls int
+}
+
+func (c *failingClient) Do(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return nil, errors.New("unexpected request")
+}
+
+func TestCreateOrderRejectedByRiskManager(t *testing.T) {
+	client := &failingClient{}
+	ledgerClient := NewLedgerX("", "", "", "", WithRiskManager(NewRiskManager(RiskLimits{MaxOrderSize: 1})))
+	ledgerClient.client = client
+
+	_, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 2, Price: 100})
+	var riskErr *RiskError
+	assert.True(t, errors.As(err, &riskErr), "should return a risk error")
+	assert.Equal(t, 0, client.calls, "should not send the order")
+}
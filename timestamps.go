@@ -0,0 +1,182 @@
+package ledgerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LedgerX sends timestamps in three wire formats. Each has a time.Time
+// based type that decodes its format and encodes back to it; NanoTime and
+// TradeTime keep enough of what they decoded to reproduce it byte for
+// byte, including whether an empty time was sent as null or "". Zero
+// values that were not decoded encode as null for LedgerTime and
+// TradeTime, and 0 for NanoTime.
+
+const ledgerTimeLayout = "2006-01-02 15:04:05+0000"
+
+// LedgerTime is a contract date such as "2021-06-25 08:00:00+0000".
+type LedgerTime struct {
+	time.Time
+}
+
+func (lt *LedgerTime) UnmarshalJSON(b []byte) (err error) {
+	s := strings.Trim(string(b), "\"")
+	if s == "null" || s == "" {
+		lt.Time = time.Time{}
+		return
+	}
+	lt.Time, err = time.Parse(ledgerTimeLayout, s)
+	return
+}
+
+func (lt LedgerTime) MarshalJSON() ([]byte, error) {
+	if lt.Time.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(fmt.Sprintf("\"%s\"", lt.Time.UTC().Format(ledgerTimeLayout))), nil
+}
+
+// NanoTime is a timestamp sent as integer nanoseconds since the Unix
+// epoch, as in action reports, open orders and heartbeats. Some messages
+// quote the number, or send null or "" for no time; a decoded NanoTime
+// encodes the same way.
+type NanoTime struct {
+	time.Time
+	quoted bool
+	null   bool // decoded from null
+	empty  bool // decoded from ""
+}
+
+// NanoTimeOf returns the NanoTime for t.
+func NanoTimeOf(t time.Time) NanoTime {
+	return NanoTime{Time: t}
+}
+
+// UnixNano returns the wire value, zero for the zero time.
+func (t NanoTime) UnixNano() int64 {
+	if t.Time.IsZero() {
+		return 0
+	}
+	return t.Time.UnixNano()
+}
+
+func (t *NanoTime) UnmarshalJSON(b []byte) error {
+	quoted := len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"'
+	s := string(bytes.Trim(b, "\""))
+	switch {
+	case string(b) == "null":
+		*t = NanoTime{null: true}
+		return nil
+	case s == "":
+		*t = NanoTime{empty: true}
+		return nil
+	case s == "0" || s == "null":
+		*t = NanoTime{quoted: quoted}
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid nanosecond timestamp %s", string(b))
+	}
+	*t = NanoTime{Time: time.Unix(0, n).UTC(), quoted: quoted}
+	return nil
+}
+
+func (t NanoTime) MarshalJSON() ([]byte, error) {
+	if t.Time.IsZero() && t.null {
+		return []byte("null"), nil
+	}
+	if t.Time.IsZero() && t.empty {
+		return []byte(`""`), nil
+	}
+	s := strconv.FormatInt(t.UnixNano(), 10)
+	if t.quoted {
+		return []byte(`"` + s + `"`), nil
+	}
+	return []byte(s), nil
+}
+
+// tradeTimeLayouts are the formats accepted for trade timestamps, most
+// common first.
+var tradeTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	ledgerTimeLayout,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// TradeTime is a trade timestamp sent as a string, usually RFC3339. The
+// string it was parsed from is kept and encoded back while the time is
+// unchanged; a changed time is formatted in the layout it was received in.
+// An empty time sent as "" encodes as "" rather than null.
+type TradeTime struct {
+	time.Time
+	layout string
+	raw    string
+	parsed time.Time
+	empty  bool // decoded from ""
+}
+
+// ParseTradeTime parses a trade timestamp in any of the formats the API
+// uses.
+func ParseTradeTime(s string) (TradeTime, error) {
+	for _, layout := range tradeTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return TradeTime{Time: t, layout: layout, raw: s, parsed: t}, nil
+		}
+	}
+	return TradeTime{}, fmt.Errorf("unrecognised trade time %q", s)
+}
+
+// TradeTimeOf returns the TradeTime for t, encoded as RFC3339.
+func TradeTimeOf(t time.Time) TradeTime {
+	return TradeTime{Time: t}
+}
+
+func (t *TradeTime) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*t = TradeTime{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid trade time %s", string(b))
+	}
+	if s == "" {
+		*t = TradeTime{empty: true}
+		return nil
+	}
+	parsed, err := ParseTradeTime(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t TradeTime) MarshalJSON() ([]byte, error) {
+	if t.Time.IsZero() && t.empty {
+		return []byte(`""`), nil
+	}
+	if t.Time.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.String())
+}
+
+// String returns the string the time was parsed from, or formats it in
+// the layout it was received in if the time has since been changed.
+func (t TradeTime) String() string {
+	if t.raw != "" && t.Time.Equal(t.parsed) {
+		return t.raw
+	}
+	layout := t.layout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return t.Time.Format(layout)
+}
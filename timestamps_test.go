@@ -0,0 +1,129 @@
+package ledgerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNanoTimeJSON(t *testing.T) {
+	var r ActionReportResponse
+	err := json.Unmarshal([]byte(`{"inserted_time":1622541600123456789,"updated_time":0,"timestamp":null}`), &r)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2021, 6, 1, 10, 0, 0, 123456789, time.UTC), r.InsertedTime.Time)
+	assert.True(t, r.UpdatedTime.IsZero())
+	assert.True(t, r.Timestamp.IsZero())
+	assert.Equal(t, int64(1622541600123456789), r.InsertedTime.UnixNano())
+
+	b, err := json.Marshal(r.InsertedTime)
+	assert.Nil(t, err)
+	assert.Equal(t, "1622541600123456789", string(b))
+	b, err = json.Marshal(r.UpdatedTime)
+	assert.Nil(t, err)
+	assert.Equal(t, "0", string(b))
+
+	for _, raw := range []string{`"1622541600123456789"`, `"0"`, `0`, `null`, `""`} {
+		var decoded NanoTime
+		assert.Nil(t, json.Unmarshal([]byte(raw), &decoded), raw)
+		b, err = json.Marshal(decoded)
+		assert.Nil(t, err)
+		assert.Equal(t, raw, string(b), "timestamps should keep their wire form")
+	}
+
+	var bad NanoTime
+	assert.NotNil(t, json.Unmarshal([]byte(`"soon"`), &bad))
+}
+
+func TestTradeTimeJSON(t *testing.T) {
+	for _, raw := range []string{
+		`"2021-06-01T10:00:00.25Z"`,
+		`"2021-06-01 10:00:00.25+00:00"`,
+		`"2021-06-01 10:00:00+0000"`,
+		`"2021-06-01T10:00:00.25"`,
+		`"2021-06-01T10:00:00.250000Z"`,
+		`"2021-06-01T10:00:00.000000Z"`,
+		`"2021-06-01T10:00:00+00:00"`,
+	} {
+		var ts TradeTime
+		assert.Nil(t, json.Unmarshal([]byte(raw), &ts), raw)
+		assert.True(t, ts.Equal(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)) || ts.Equal(time.Date(2021, 6, 1, 10, 0, 0, 250e6, time.UTC)), raw)
+
+		b, err := json.Marshal(ts)
+		assert.Nil(t, err)
+		assert.Equal(t, raw, string(b))
+	}
+
+	for _, raw := range []string{`""`, `null`} {
+		var empty TradeTime
+		assert.Nil(t, json.Unmarshal([]byte(raw), &empty), raw)
+		assert.True(t, empty.IsZero())
+		b, err := json.Marshal(empty)
+		assert.Nil(t, err)
+		assert.Equal(t, raw, string(b), "empty times should keep their wire form")
+	}
+	b, err := json.Marshal(TradeTime{})
+	assert.Nil(t, err)
+	assert.Equal(t, "null", string(b))
+
+	var bad TradeTime
+	assert.NotNil(t, json.Unmarshal([]byte(`"yesterday"`), &bad))
+	assert.NotNil(t, json.Unmarshal([]byte(`12`), &bad))
+
+	b, err = json.Marshal(TradeTimeOf(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)))
+	assert.Nil(t, err)
+	assert.Equal(t, `"2021-06-01T10:00:00Z"`, string(b))
+
+	// A changed time is formatted in the layout it was received in.
+	changed, err := ParseTradeTime("2021-06-01 10:00:00.25+00:00")
+	assert.Nil(t, err)
+	changed.Time = changed.Add(time.Second)
+	assert.Equal(t, "2021-06-01 10:00:01.25+00:00", changed.String())
+}
+
+func TestTradesFixtureRoundTrip(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/trades.json")
+	assert.Nil(t, err)
+	var raw struct {
+		Data json.RawMessage `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(fixture, &raw))
+	var expected bytes.Buffer
+	assert.Nil(t, json.Compact(&expected, raw.Data))
+
+	var trades []ListTradeData
+	assert.Nil(t, json.Unmarshal(raw.Data, &trades))
+	b, err := json.Marshal(trades)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.String(), string(b))
+}
+
+func TestResponsesRoundTrip(t *testing.T) {
+	raw := `{"id":1,"contract_id":"7","created":"2021-06-01 10:00:00.5+00:00","timestamp":"2021-06-01T10:00:00.5Z"}`
+	var trade ListTradeData
+	assert.Nil(t, json.Unmarshal([]byte(raw), &trade))
+	b, err := json.Marshal(trade)
+	assert.Nil(t, err)
+	var again ListTradeData
+	assert.Nil(t, json.Unmarshal(b, &again))
+	assert.Equal(t, trade, again)
+	assert.True(t, trade.Created.Equal(trade.Timestamp.Time))
+
+	contract := ListContractsData{DateExpires: LedgerTime{time.Date(2021, 6, 25, 8, 0, 0, 0, time.UTC)}}
+	b, err = json.Marshal(contract)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"date_expires":"2021-06-25 08:00:00+0000"`)
+	var decoded ListContractsData
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.True(t, decoded.DateExpires.Equal(contract.DateExpires.Time))
+
+	order := ListOpenOrdersData{Mid: "m", InsertedTime: NanoTimeOf(time.Unix(0, 1622541600000000001).UTC())}
+	b, err = json.Marshal(order)
+	assert.Nil(t, err)
+	var decodedOrder ListOpenOrdersData
+	assert.Nil(t, json.Unmarshal(b, &decodedOrder))
+	assert.Equal(t, order, decodedOrder)
+}
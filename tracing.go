@@ -0,0 +1,397 @@
+package ledgerx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext identifies a span using W3C trace context identifiers, so
+// exported spans can be forwarded to an OpenTelemetry collector.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// Traceparent formats the context as a W3C traceparent header value.
+func (c SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", c.TraceID, c.SpanID)
+}
+
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+type SpanStatus string
+
+const (
+	SpanStatusUnset SpanStatus = ""
+	SpanStatusOK    SpanStatus = "ok"
+	SpanStatusError SpanStatus = "error"
+)
+
+// SpanData is the immutable record of a finished span handed to exporters.
+type SpanData struct {
+	Name          string
+	Context       SpanContext
+	Parent        SpanID
+	Links         []SpanContext
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	Events        []SpanEvent
+	Status        SpanStatus
+	StatusMessage string
+}
+
+// SpanExporter receives spans as they end.
+type SpanExporter interface {
+	ExportSpan(span SpanData)
+}
+
+// InMemoryExporter keeps finished spans in memory, for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Span is an in-progress span. All methods are safe on a nil span.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) AddEvent(name string, attributes map[string]interface{}) {
+	s.addEventAt(name, time.Now(), attributes)
+}
+
+func (s *Span) addEventAt(name string, at time.Time, attributes map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Events = append(s.data.Events, SpanEvent{Name: name, Time: at, Attributes: attributes})
+}
+
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = SpanStatusError
+	s.data.StatusMessage = err.Error()
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if s.data.Status == SpanStatusUnset {
+		s.data.Status = SpanStatusOK
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.exporter.ExportSpan(data)
+}
+
+// DefaultOrderSpanTTL is the default Tracer.OrderSpanTTL.
+const DefaultOrderSpanTTL = 24 * time.Hour
+
+// Tracer creates spans for order flows. Submitting an order starts an
+// order span which stays open while action reports for its mid are
+// recorded as events, and ends when the order is filled or cancelled.
+// Reports that arrive before the CreateOrder response are buffered and
+// attached once the mid is known. CancelOrder and CancelAndReplaceOrder
+// spans are children of the order span. All methods are safe on a nil
+// Tracer, which disables tracing.
+type Tracer struct {
+	exporter SpanExporter
+
+	// OrderSpanTTL ends order spans that have not seen a terminal action
+	// report this long after the submit, with an "expired" event. Expired
+	// spans are swept on heartbeats and submits. Zero disables expiry.
+	OrderSpanTTL time.Duration
+
+	mu     sync.Mutex
+	orders map[string]*Span
+	early  earlyReports
+}
+
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{
+		exporter:     exporter,
+		OrderSpanTTL: DefaultOrderSpanTTL,
+		orders:       make(map[string]*Span),
+	}
+}
+
+// WithTracer traces order flows and correlates them with action reports.
+func WithTracer(t *Tracer) Option {
+	return func(l *LedgerX) {
+		l.tracer = t
+		if t != nil {
+			l.handlers = append(l.handlers, t)
+		}
+	}
+}
+
+// Start begins a span. A nil parent starts a new trace.
+func (t *Tracer) Start(name string, parent *SpanContext, links ...SpanContext) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Links:      links,
+			Start:      time.Now(),
+			Attributes: make(map[string]interface{}),
+		},
+	}
+	if parent != nil {
+		span.data.Context.TraceID = parent.TraceID
+		span.data.Parent = parent.SpanID
+	} else {
+		rand.Read(span.data.Context.TraceID[:])
+	}
+	rand.Read(span.data.Context.SpanID[:])
+	return span
+}
+
+// OrderSpan returns the open order span for mid, if it is being traced.
+func (t *Tracer) OrderSpan(mid string) (*Span, bool) {
+	if t == nil {
+		return nil, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span, ok := t.orders[mid]
+	return span, ok
+}
+
+func (t *Tracer) traceCreateOrder(request *CreateOrderRequest, submit func() (*CreateOrderResponse, error)) (*CreateOrderResponse, error) {
+	if t == nil {
+		return submit()
+	}
+	t.expireOrders(time.Now())
+
+	orderSpan := t.Start("ledgerx.order", nil)
+	orderSpan.SetAttribute("contract_id", request.ContractID)
+	orderSpan.SetAttribute("is_ask", request.IsAsk)
+	orderSpan.SetAttribute("size", request.Size)
+	orderSpan.SetAttribute("price", request.Price)
+	parent := orderSpan.Context()
+	submitSpan := t.Start("ledgerx.CreateOrder", &parent)
+
+	// Buffer reports for unknown mids until the submit returns.
+	t.mu.Lock()
+	t.early.begin()
+	t.mu.Unlock()
+
+	resp, err := submit()
+
+	t.mu.Lock()
+	var early []earlyReport
+	if err == nil {
+		early = t.early.end(resp.Data.Mid)
+		t.orders[resp.Data.Mid] = orderSpan
+	} else {
+		t.early.end("")
+	}
+	t.mu.Unlock()
+
+	if err != nil {
+		submitSpan.RecordError(err)
+		submitSpan.End()
+		orderSpan.RecordError(err)
+		orderSpan.End()
+		return nil, err
+	}
+
+	submitSpan.SetAttribute("mid", resp.Data.Mid)
+	submitSpan.End()
+	orderSpan.SetAttribute("mid", resp.Data.Mid)
+	orderSpan.AddEvent("submitted", nil)
+	for _, e := range early {
+		t.recordReport(orderSpan, e.report, e.at)
+	}
+	return resp, nil
+}
+
+func (t *Tracer) traceOrderCall(name string, mid string, call func() error) error {
+	if t == nil {
+		return call()
+	}
+
+	var span *Span
+	if orderSpan, ok := t.OrderSpan(mid); ok {
+		parent := orderSpan.Context()
+		span = t.Start(name, &parent)
+		orderSpan.AddEvent(name, nil)
+	} else {
+		span = t.Start(name, nil)
+	}
+	span.SetAttribute("mid", mid)
+
+	err := call()
+	span.RecordError(err)
+	span.End()
+	return err
+}
+
+func (t *Tracer) HandleMessage(msg Message) {
+	if t == nil {
+		return
+	}
+	if msg.Type == ChanHeartbeat {
+		t.expireOrders(time.Now())
+		return
+	}
+	report, ok := msg.Data.(ActionReportResponse)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	span, ok := t.orders[report.MessageID]
+	if !ok {
+		t.early.add(report, time.Now())
+	}
+	t.mu.Unlock()
+	if ok {
+		t.recordReport(span, report, time.Now())
+	}
+}
+
+func (t *Tracer) recordReport(span *Span, report ActionReportResponse, at time.Time) {
+	event, ok := orderStatusEvents[report.StatusType]
+	if !ok {
+		event = "rejected"
+	}
+	span.addEventAt("exchange."+event, at, map[string]interface{}{
+		"status_type":   report.StatusType,
+		"status_reason": report.StatusReason,
+		"size":          report.Size,
+		"filled_size":   report.FilledSize,
+		"filled_price":  report.FilledPrice,
+	})
+
+	if isTerminalReport(report) {
+		if event == "rejected" {
+			span.RecordError(fmt.Errorf("order rejected with status %d", report.StatusType))
+		}
+		t.endOrder(report.MessageID, span)
+	}
+}
+
+// expireOrders ends order spans older than OrderSpanTTL.
+func (t *Tracer) expireOrders(now time.Time) {
+	if t == nil || t.OrderSpanTTL <= 0 {
+		return
+	}
+	t.mu.Lock()
+	expired := make(map[string]*Span)
+	for mid, span := range t.orders {
+		if now.Sub(span.data.Start) > t.OrderSpanTTL {
+			expired[mid] = span
+		}
+	}
+	t.mu.Unlock()
+
+	for mid, span := range expired {
+		span.AddEvent("expired", nil)
+		t.endOrder(mid, span)
+	}
+}
+
+func (t *Tracer) endOrder(mid string, span *Span) {
+	t.mu.Lock()
+	if t.orders[mid] == span {
+		delete(t.orders, mid)
+	}
+	t.mu.Unlock()
+	span.End()
+}
+
+// isTerminalReport reports whether no further action reports are expected
+// for the order.
+func isTerminalReport(report ActionReportResponse) bool {
+	switch report.StatusType {
+	case StatusCodeOrderCancelled, StatusCodeMarketOrderNotFilled:
+		return true
+	case StatusCodeTradeOccured:
+		return report.Size == 0
+	case StatusCodeOrderInserted, StatusCodeOrderCancelledAndReplaced:
+		return false
+	default:
+		return true
+	}
+}
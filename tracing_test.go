@@ -0,0 +1,153 @@
+package ledgerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func findSpan(spans []SpanData, name string) (SpanData, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return SpanData{}, false
+}
+
+func TestTracerCorrelatesOrderFlow(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/api/orders" {
+			w.Write([]byte(`{"data": {"mid": "abc"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	exporter := NewInMemoryExporter()
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithTracer(NewTracer(exporter)))
+
+	_, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 2, Price: 100})
+	assert.Nil(t, err, "should create order")
+
+	submit, ok := findSpan(exporter.Spans(), "ledgerx.CreateOrder")
+	assert.True(t, ok, "submit span should end when the REST call returns")
+	_, ok = findSpan(exporter.Spans(), "ledgerx.order")
+	assert.False(t, ok, "order span should stay open until the order completes")
+
+	assert.Nil(t, ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "abc", "status_type": 200, "size": 2}`)))
+	assert.Nil(t, ledgerClient.CancelAndReplaceOrder("abc", &CancelAndReplaceRequest{ContractID: 1, Size: 2, Price: 110}))
+	assert.Nil(t, ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "abc", "status_type": 201, "size": 0, "filled_size": 2, "filled_price": 110}`)))
+
+	spans := exporter.Spans()
+	order, ok := findSpan(spans, "ledgerx.order")
+	if assert.True(t, ok, "order span should end on full fill") {
+		assert.Equal(t, "abc", order.Attributes["mid"])
+		assert.Equal(t, submit.Context.TraceID, order.Context.TraceID)
+		assert.Equal(t, order.Context.SpanID, submit.Parent)
+
+		var events []string
+		for _, event := range order.Events {
+			events = append(events, event.Name)
+		}
+		assert.Equal(t, []string{"submitted", "exchange.inserted", "ledgerx.CancelAndReplaceOrder", "exchange.traded"}, events)
+		assert.Equal(t, SpanStatusOK, order.Status)
+	}
+
+	replace, ok := findSpan(spans, "ledgerx.CancelAndReplaceOrder")
+	if assert.True(t, ok) {
+		assert.Equal(t, order.Context.TraceID, replace.Context.TraceID)
+		assert.Equal(t, order.Context.SpanID, replace.Parent)
+	}
+}
+
+func TestTracerAttachesReportsBeforeResponse(t *testing.T) {
+	var ledgerClient *LedgerX
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The exchange fills the order before the REST response arrives.
+		ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "early", "status_type": 200, "size": 2}`))
+		ledgerClient.handleMessage([]byte(`{"type": "action_report", "mid": "early", "status_type": 201, "size": 0, "filled_size": 2, "filled_price": 100}`))
+		w.Write([]byte(`{"data": {"mid": "early"}}`))
+	}))
+	defer s.Close()
+
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+	ledgerClient = NewLedgerX("", s.URL, s.URL, "", WithTracer(tracer))
+
+	_, err := ledgerClient.CreateOrder(Buy(1, 2).Limit(100))
+	assert.Nil(t, err)
+
+	order, ok := findSpan(exporter.Spans(), "ledgerx.order")
+	if assert.True(t, ok, "order span should end on the buffered fill") {
+		var events []string
+		for _, event := range order.Events {
+			events = append(events, event.Name)
+		}
+		assert.Equal(t, []string{"submitted", "exchange.inserted", "exchange.traded"}, events)
+	}
+	_, open := tracer.OrderSpan("early")
+	assert.False(t, open)
+	assert.Empty(t, tracer.early, "buffer should be cleared once no submit is pending")
+}
+
+func TestTracerExpiresOrderSpans(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"mid": "stale"}}`))
+	}))
+	defer s.Close()
+
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+	tracer.OrderSpanTTL = time.Millisecond
+	ledgerClient := NewLedgerX("", s.URL, s.URL, "", WithTracer(tracer))
+
+	_, err := ledgerClient.CreateOrder(Buy(1, 2).Limit(100))
+	assert.Nil(t, err)
+	_, open := tracer.OrderSpan("stale")
+	assert.True(t, open)
+
+	time.Sleep(5 * time.Millisecond)
+	tracer.HandleMessage(Message{Type: ChanHeartbeat, Data: HeartbeatMessage{}})
+
+	_, open = tracer.OrderSpan("stale")
+	assert.False(t, open, "span should be dropped after the TTL")
+	order, ok := findSpan(exporter.Spans(), "ledgerx.order")
+	if assert.True(t, ok) {
+		assert.Equal(t, "expired", order.Events[len(order.Events)-1].Name)
+	}
+}
+
+func TestTracerRecordsSubmitErrors(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	ledgerClient := NewLedgerX("", "", "", "", WithTracer(NewTracer(exporter)))
+	ledgerClient.Halt("test")
+
+	_, err := ledgerClient.CreateOrder(&CreateOrderRequest{ContractID: 1, Size: 2, Price: 100})
+	assert.NotNil(t, err)
+
+	order, ok := findSpan(exporter.Spans(), "ledgerx.order")
+	if assert.True(t, ok, "failed orders should end their span") {
+		assert.Equal(t, SpanStatusError, order.Status)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	ledgerClient := NewLedgerX("", "", "", "", WithTracer(nil))
+	assert.Empty(t, ledgerClient.handlers)
+
+	var tracer *Tracer
+	assert.NotPanics(t, func() {
+		tracer.HandleMessage(Message{Type: ChanHeartbeat})
+		tracer.HandleMessage(Message{Data: ActionReportResponse{MessageID: "m", StatusType: StatusCodeOrderInserted}})
+	})
+}
+
+func TestSpanContextTraceparent(t *testing.T) {
+	ctx := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}}
+	assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-01", ctx.Traceparent())
+}
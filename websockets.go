@@ -8,7 +8,6 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 // Connect to the Kraken API, this should only be called once.
@@ -26,6 +25,9 @@ func (l *LedgerX) Connect() error {
 	return nil
 }
 
+// Listen returns the channel websocket messages are delivered on. It must
+// be drained: when it is full the read loop, and with it every message
+// handler, waits for the consumer unless WithDropOnFullChannel is set.
 func (l *LedgerX) Listen() <-chan Message {
 	return l.msg
 }
@@ -79,10 +81,11 @@ func (l *LedgerX) managerThread() {
 		case <-l.connect:
 			time.Sleep(l.reconnectTimeout)
 
-			log.Warnf("reconnecting ledgerx...")
+			l.logger.Warn("reconnecting ledgerx websocket")
+			l.metrics.observeReconnect()
 
 			if err := l.dial(); err != nil {
-				log.Error(err)
+				l.logger.Error("ledgerx websocket dial failed", "error", err)
 				l.connect <- struct{}{}
 				continue
 			}
@@ -92,7 +95,8 @@ func (l *LedgerX) managerThread() {
 		case <-heartbeat.C:
 			err := l.conn.WriteMessage(websocket.TextMessage, []byte("pong"))
 			if err != nil {
-				log.Println(err)
+				l.logger.Error("ledgerx websocket heartbeat failed", "error", err)
+				l.handleDisconnect()
 				l.connect <- struct{}{}
 			}
 		}
@@ -107,7 +111,7 @@ func (l *LedgerX) listenSocket() {
 	}
 
 	if err := l.conn.SetReadDeadline(time.Now().Add(l.readTimeout)); err != nil {
-		log.Error(err)
+		l.logger.Error("ledgerx websocket set read deadline failed", "error", err)
 		return
 	}
 
@@ -118,21 +122,22 @@ func (l *LedgerX) listenSocket() {
 		default:
 			_, msg, err := l.conn.ReadMessage()
 			if err != nil {
-				log.Error(err)
+				l.logger.Error("ledgerx websocket read failed", "error", err)
+				l.handleDisconnect()
 				l.connect <- struct{}{}
 				return
 			}
 
 			if err := l.conn.SetReadDeadline(time.Now().Add(l.readTimeout)); err != nil {
-				log.Error(err)
+				l.logger.Error("ledgerx websocket set read deadline failed", "error", err)
 				l.connect <- struct{}{}
 				return
 			}
 
-			log.Tracef("server->client: %s", string(msg))
+			l.logger.Debug("ledgerx websocket message", "message", string(msg))
 
 			if err := l.handleMessage(msg); err != nil {
-				log.Error(err)
+				l.logger.Error("ledgerx websocket message handling failed", "error", err)
 			}
 		}
 	}
@@ -148,6 +153,7 @@ func (l *LedgerX) handleMessage(data []byte) error {
 	if err != nil {
 		return errors.Errorf("Error during unmarshal response: %s", string(data))
 	}
+	l.metrics.observeMessage(res.Type)
 
 	switch res.Type {
 	case ChanBookTop:
@@ -156,52 +162,53 @@ func (l *LedgerX) handleMessage(data []byte) error {
 		if err != nil {
 			return errors.Errorf("Error during unmarshal TopBookResponse: %s", string(data))
 		}
-		l.msg <- Message{
+		l.publish(Message{
 			Type: ChanBookTop,
 			Data: jsonRes,
-		}
+		})
 	case ChanActionReport:
 		var jsonRes ActionReportResponse
 		err := json.Unmarshal(data, &jsonRes)
 		if err != nil {
 			return errors.Errorf("Error during unmarshal ActionReportResponse: %s", string(data))
 		}
-		//log.Println(string(data))
-		l.msg <- Message{
+		l.metrics.observeActionReport(jsonRes)
+		l.logger.Debug("ledgerx action report", "channel", ChanActionReport, "contract_id", jsonRes.ContractID, "mid", jsonRes.MessageID, "status_type", jsonRes.StatusType)
+		l.publish(Message{
 			Type: ChanActionReport,
 			Data: jsonRes,
-		}
+		})
 	case ChanBalanceUpdate:
 		var jsonRes BalanceUpdateMessage
-		//log.Println(string(data))
 		err := json.Unmarshal(data, &jsonRes)
 		if err != nil {
 			return errors.Errorf("Error during unmarshal BalanceUpdateMessage: %s", string(data))
 		}
-		l.msg <- Message{
+		l.publish(Message{
 			Type: ChanBalanceUpdate,
 			Data: jsonRes,
-		}
+		})
 	case ChanOpenPositionsUpdate:
 		var jsonRes OpenPositionsMessage
 		err := json.Unmarshal(data, &jsonRes)
 		if err != nil {
 			return errors.Errorf("Error during unmarshal OpenPositionsMessage: %s", string(data))
 		}
-		l.msg <- Message{
+		l.publish(Message{
 			Type: ChanOpenPositionsUpdate,
 			Data: jsonRes,
-		}
+		})
 	case ChanHeartbeat:
 		var jsonRes HeartbeatMessage
 		err := json.Unmarshal(data, &jsonRes)
 		if err != nil {
 			return errors.Errorf("Error during unmarshal HeartbeatMessage: %s", string(data))
 		}
-		l.msg <- Message{
+		l.metrics.observeHeartbeat(jsonRes)
+		l.publish(Message{
 			Type: ChanHeartbeat,
 			Data: jsonRes,
-		}
+		})
 	default:
 		if handleInfoMessage(res.Type, data) == false {
 			return errors.Errorf("Unexpected message: %s", string(data))
@@ -210,6 +217,55 @@ func (l *LedgerX) handleMessage(data []byte) error {
 	return nil
 }
 
+// WithDropOnFullChannel drops websocket messages instead of blocking the
+// read loop when the Listen channel is full. Dropped messages, including
+// action reports, are logged and counted in the metrics; handlers still
+// receive every message.
+func WithDropOnFullChannel() Option {
+	return func(l *LedgerX) {
+		l.dropOnFullChannel = true
+	}
+}
+
+// MessageHandler observes decoded websocket messages. Handlers are called
+// synchronously from the read loop and must not block.
+type MessageHandler interface {
+	HandleMessage(msg Message)
+}
+
+// AddMessageHandler registers a handler after construction, for
+// components such as PositionBook that need the client to be created first.
+func (l *LedgerX) AddMessageHandler(h MessageHandler) {
+	l.handlersMu.Lock()
+	defer l.handlersMu.Unlock()
+	l.handlers = append(l.handlers, h)
+}
+
+// publish delivers msg to the handlers and the Listen channel. When the
+// channel is full the read loop waits for the consumer, unless the client
+// was created with WithDropOnFullChannel.
+func (l *LedgerX) publish(msg Message) {
+	l.handlersMu.RLock()
+	handlers := l.handlers
+	l.handlersMu.RUnlock()
+
+	for _, h := range handlers {
+		h.HandleMessage(msg)
+	}
+	if !l.dropOnFullChannel {
+		l.msg <- msg
+		l.metrics.observeChannel(len(l.msg), "")
+		return
+	}
+	select {
+	case l.msg <- msg:
+		l.metrics.observeChannel(len(l.msg), "")
+	default:
+		l.metrics.observeChannel(len(l.msg), msg.Type)
+		l.logger.Warn("ledgerx listen channel full, dropping message", "channel", msg.Type)
+	}
+}
+
 func handleInfoMessage(messageType string, data []byte) bool {
 	switch messageType {
 	case ChanAuthSuccess: